@@ -0,0 +1,240 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/auth"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+	"github.com/labstack/echo/v4"
+)
+
+type StubStore struct {
+	keys   map[string]Key
+	nextID int
+}
+
+func NewStubStore() *StubStore {
+	return &StubStore{keys: map[string]Key{}}
+}
+
+func (s *StubStore) CreateAPIKey(ctx context.Context, key Key, hash string) (Key, error) {
+	s.nextID++
+	key.ID = s.nextID
+	key.CreatedAt = time.Now()
+	s.keys[hash] = key
+	return key, nil
+}
+
+func (s *StubStore) APIKeys(ctx context.Context) ([]Key, error) {
+	var keys []Key
+	for _, k := range s.keys {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (s *StubStore) APIKeyByHash(ctx context.Context, hash string) (Key, error) {
+	k, ok := s.keys[hash]
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+	return k, nil
+}
+
+func (s *StubStore) RevokeAPIKey(ctx context.Context, id int) error {
+	for hash, k := range s.keys {
+		if k.ID == id {
+			now := time.Now()
+			k.RevokedAt = &now
+			s.keys[hash] = k
+			return nil
+		}
+	}
+	return ErrKeyNotFound
+}
+
+// FailingStore fails every call, like a store whose database is down.
+type FailingStore struct {
+	err error
+}
+
+func (s FailingStore) CreateAPIKey(ctx context.Context, key Key, hash string) (Key, error) {
+	return Key{}, s.err
+}
+
+func (s FailingStore) APIKeys(ctx context.Context) ([]Key, error) {
+	return nil, s.err
+}
+
+func (s FailingStore) APIKeyByHash(ctx context.Context, hash string) (Key, error) {
+	return Key{}, s.err
+}
+
+func (s FailingStore) RevokeAPIKey(ctx context.Context, id int) error {
+	return s.err
+}
+
+func newContext(method, target, body string, p auth.Principal) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	e.Validator = wallet.NewValidator()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetPrincipal(c, p)
+	return c, rec
+}
+
+func serve(c echo.Context, h echo.HandlerFunc) {
+	if err := h(c); err != nil {
+		wallet.ErrorHandler(err, c)
+	}
+}
+
+var admin = auth.Principal{UserID: 1, Roles: []string{auth.RoleAdmin}}
+
+func TestCreateKey(t *testing.T) {
+	t.Run("given admin should issue key that authenticates with its scopes", func(t *testing.T) {
+		store := NewStubStore()
+		c, rec := newContext(http.MethodPost, "/api/v1/api-keys", `{"name": "batch", "scopes": ["wallets:read", "transfers:create"]}`, admin)
+
+		serve(c, New(store).CreateKeyHandler)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d and %s", rec.Code, rec.Body.String())
+		}
+		var got Created
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("expected created key, got %s", rec.Body.String())
+		}
+		if !strings.HasPrefix(got.Secret, got.Prefix) || len(got.Secret) <= prefixLength {
+			t.Errorf("expected key starting with prefix %q, got %q", got.Prefix, got.Secret)
+		}
+		if _, stored := store.keys[got.Secret]; stored {
+			t.Errorf("expected only the hash to be stored")
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(Header, got.Secret)
+		p, ok, err := NewAuthenticator(store).Authenticate(req)
+		if err != nil || !ok {
+			t.Fatalf("expected key to authenticate, got %v %v", ok, err)
+		}
+		expected := auth.Principal{KeyID: got.ID, Scopes: []string{auth.ScopeWalletsRead, auth.ScopeTransfersCreate}}
+		if !reflect.DeepEqual(expected, p) {
+			t.Errorf("expected %v, got %v", expected, p)
+		}
+	})
+
+	t.Run("given unknown scope should return 400", func(t *testing.T) {
+		c, rec := newContext(http.MethodPost, "/api/v1/api-keys", `{"name": "batch", "scopes": ["wallets:admin"]}`, admin)
+
+		serve(c, New(NewStubStore()).CreateKeyHandler)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given non admin caller should return 403", func(t *testing.T) {
+		for _, p := range []auth.Principal{{UserID: 2}, {KeyID: 1, Scopes: auth.Scopes}} {
+			c, rec := newContext(http.MethodPost, "/api/v1/api-keys", `{"name": "batch", "scopes": ["wallets:read"]}`, p)
+
+			serve(c, New(NewStubStore()).CreateKeyHandler)
+
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("expected 403 for %v, got %d and %s", p, rec.Code, rec.Body.String())
+			}
+		}
+	})
+}
+
+func TestRevokeKey(t *testing.T) {
+	t.Run("given revoked key should no longer authenticate", func(t *testing.T) {
+		store := NewStubStore()
+		key, _ := store.CreateAPIKey(context.Background(), Key{Name: "batch", Scopes: []string{auth.ScopeWalletsRead}}, Hash("wk_secret"))
+		c, rec := newContext(http.MethodDelete, "/api/v1/api-keys/1", "", admin)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		serve(c, New(store).RevokeKeyHandler)
+
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d and %s", rec.Code, rec.Body.String())
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(Header, "wk_secret")
+		if _, ok, err := NewAuthenticator(store).Authenticate(req); !ok || !errors.Is(err, auth.ErrInvalidCredentials) {
+			t.Errorf("expected key %d to be rejected", key.ID)
+		}
+	})
+
+	t.Run("given unknown key should return 404", func(t *testing.T) {
+		c, rec := newContext(http.MethodDelete, "/api/v1/api-keys/9", "", admin)
+		c.SetParamNames("id")
+		c.SetParamValues("9")
+
+		serve(c, New(NewStubStore()).RevokeKeyHandler)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestKeys(t *testing.T) {
+	t.Run("given keys should list them without secrets", func(t *testing.T) {
+		store := NewStubStore()
+		store.CreateAPIKey(context.Background(), Key{Name: "batch", Prefix: "wk_abcdefgh", Scopes: []string{auth.ScopeWalletsRead}}, Hash("wk_secret"))
+		c, rec := newContext(http.MethodGet, "/api/v1/api-keys", "", admin)
+
+		serve(c, New(store).KeysHandler)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d and %s", rec.Code, rec.Body.String())
+		}
+		if strings.Contains(rec.Body.String(), "wk_secret") || !strings.Contains(rec.Body.String(), "wk_abcdefgh") {
+			t.Errorf("expected prefix only, got %s", rec.Body.String())
+		}
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Run("given no header should defer to other authenticators", func(t *testing.T) {
+		_, ok, err := NewAuthenticator(NewStubStore()).Authenticate(httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if ok || err != nil {
+			t.Errorf("expected no credentials, got %v %v", ok, err)
+		}
+	})
+
+	t.Run("given unknown key should fail", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(Header, "wk_unknown")
+
+		_, ok, err := NewAuthenticator(NewStubStore()).Authenticate(req)
+
+		if !ok || !errors.Is(err, auth.ErrInvalidCredentials) {
+			t.Errorf("expected invalid credentials, got %v %v", ok, err)
+		}
+	})
+
+	t.Run("given store failure should not report invalid credentials", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(Header, "wk_unknown")
+
+		_, ok, err := NewAuthenticator(FailingStore{errors.New("connection refused")}).Authenticate(req)
+
+		if !ok || err == nil || errors.Is(err, auth.ErrInvalidCredentials) {
+			t.Errorf("expected store error, got %v %v", ok, err)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package apikey
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/auth"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+	"github.com/labstack/echo/v4"
+)
+
+type Handler struct {
+	store Storer
+}
+
+func New(store Storer) *Handler {
+	return &Handler{store: store}
+}
+
+type CreateRequest struct {
+	Name   string   `json:"name" example:"nightly-settlement" validate:"required,notblank,max=100"`
+	Scopes []string `json:"scopes" example:"wallets:read,transfers:create" validate:"required,min=1"`
+}
+
+// Created is returned once, when the key is issued.
+type Created struct {
+	Key
+	Secret string `json:"key" example:"wk_3fA9x0Qe..."`
+}
+
+var errAdminOnly = wallet.Forbidden("admin role required")
+
+// requireAdmin only lets admin users manage keys, never API keys.
+func requireAdmin(c echo.Context) error {
+	p, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+	if !p.IsAdmin() {
+		return errAdminOnly
+	}
+	return nil
+}
+
+// CreateKeyHandler
+//
+//	@Summary		Issue API key
+//	@Description	Issue an API key for a service caller. The key is only returned in this response.
+//	@Tags			api-key
+//	@Accept			json
+//	@Produce		json
+//	@Param			key	body	CreateRequest	true	"Key name and scopes"
+//	@Success		201	{object}	Created
+//	@Failure		400	{object}	wallet.Err
+//	@Failure		401	{object}	wallet.Err
+//	@Failure		403	{object}	wallet.Err
+//	@Failure		500	{object}	wallet.Err
+//	@Security		BearerAuth
+//	@Router			/api/v1/api-keys [post]
+func (h *Handler) CreateKeyHandler(c echo.Context) error {
+	if err := requireAdmin(c); err != nil {
+		return err
+	}
+	var req CreateRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	for _, scope := range req.Scopes {
+		if !validScope(scope) {
+			return wallet.NewValidationError("scopes", "must be one of "+strings.Join(auth.Scopes, ", "))
+		}
+	}
+
+	secret, err := generate()
+	if err != nil {
+		return err
+	}
+	key, err := h.store.CreateAPIKey(c.Request().Context(), Key{
+		Name:   strings.TrimSpace(req.Name),
+		Prefix: secret[:prefixLength],
+		Scopes: req.Scopes,
+	}, Hash(secret))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, Created{Key: key, Secret: secret})
+}
+
+// KeysHandler
+//
+//	@Summary		List API keys
+//	@Description	List issued API keys, including revoked ones. Keys themselves are never returned.
+//	@Tags			api-key
+//	@Produce		json
+//	@Success		200	{array}	Key
+//	@Failure		401	{object}	wallet.Err
+//	@Failure		403	{object}	wallet.Err
+//	@Failure		500	{object}	wallet.Err
+//	@Security		BearerAuth
+//	@Router			/api/v1/api-keys [get]
+func (h *Handler) KeysHandler(c echo.Context) error {
+	if err := requireAdmin(c); err != nil {
+		return err
+	}
+	keys, err := h.store.APIKeys(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	if keys == nil {
+		keys = []Key{}
+	}
+	return c.JSON(http.StatusOK, keys)
+}
+
+// RevokeKeyHandler
+//
+//	@Summary		Revoke API key
+//	@Description	Revoke an API key. Requests using it are rejected from then on.
+//	@Tags			api-key
+//	@Param			id	path	int	true	"API key id"
+//	@Success		204
+//	@Failure		400	{object}	wallet.Err
+//	@Failure		401	{object}	wallet.Err
+//	@Failure		403	{object}	wallet.Err
+//	@Failure		404	{object}	wallet.Err
+//	@Failure		500	{object}	wallet.Err
+//	@Security		BearerAuth
+//	@Router			/api/v1/api-keys/{id} [delete]
+func (h *Handler) RevokeKeyHandler(c echo.Context) error {
+	if err := requireAdmin(c); err != nil {
+		return err
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return wallet.NewValidationError("id", "must be an API key id")
+	}
+	if err := h.store.RevokeAPIKey(c.Request().Context(), id); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func validScope(scope string) bool {
+	for _, s := range auth.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
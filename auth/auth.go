@@ -0,0 +1,151 @@
+// Package auth authenticates API callers and exposes the resulting
+// Principal to handlers through the Echo context.
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/logging"
+	"github.com/labstack/echo/v4"
+)
+
+const RoleAdmin = "admin"
+
+// Scopes granted to API keys. Users authenticated with a JWT are not
+// limited by scopes; ownership checks apply to them instead.
+const (
+	ScopeWalletsRead     = "wallets:read"
+	ScopeWalletsWrite    = "wallets:write"
+	ScopeTransfersCreate = "transfers:create"
+	ScopeLedgerRead      = "ledger:read"
+	ScopeUsersRead       = "users:read"
+	ScopeUsersWrite      = "users:write"
+)
+
+// Scopes lists every scope an API key may be issued.
+var Scopes = []string{ScopeWalletsRead, ScopeWalletsWrite, ScopeTransfersCreate, ScopeLedgerRead, ScopeUsersRead, ScopeUsersWrite}
+
+const principalKey = "auth.principal"
+
+// ErrInvalidCredentials is wrapped by authenticator errors that reject the
+// credentials themselves, as opposed to failing to check them.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Principal is the authenticated caller of a request: either a user
+// identified by a JWT or a service identified by an API key.
+type Principal struct {
+	// UserID is the token subject. It is zero for API keys.
+	UserID int
+	Roles  []string
+	// KeyID is the API key used by a service caller.
+	KeyID  int
+	Scopes []string
+}
+
+func (p Principal) HasRole(role string) bool {
+	return contains(p.Roles, role)
+}
+
+// IsAdmin reports whether the caller may act on any user's wallets.
+func (p Principal) IsAdmin() bool {
+	return p.HasRole(RoleAdmin)
+}
+
+// IsService reports whether the caller authenticated with an API key.
+// Services act on any user's wallets within their scopes.
+func (p Principal) IsService() bool {
+	return p.KeyID != 0
+}
+
+func (p Principal) HasScope(scope string) bool {
+	return contains(p.Scopes, scope)
+}
+
+// ID identifies the caller across requests, for example "user:1" or "key:3".
+func (p Principal) ID() string {
+	if p.IsService() {
+		return "key:" + strconv.Itoa(p.KeyID)
+	}
+	return "user:" + strconv.Itoa(p.UserID)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func SetPrincipal(c echo.Context, p Principal) {
+	c.Set(principalKey, p)
+}
+
+// PrincipalFrom returns the caller set by an authentication middleware.
+func PrincipalFrom(c echo.Context) (Principal, bool) {
+	p, ok := c.Get(principalKey).(Principal)
+	return p, ok
+}
+
+// Authenticator identifies the caller of a request. It returns false when
+// the request carries no credentials it understands. Credentials it
+// rejects are reported with an error wrapping ErrInvalidCredentials; any
+// other error means they could not be checked.
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, bool, error)
+}
+
+// Middleware rejects requests that none of the authenticators accept and
+// stores the caller for PrincipalFrom and the request log. Authenticators
+// are tried in order. Errors other than ErrInvalidCredentials are returned
+// for the HTTPErrorHandler, so an outage is not reported as a bad key.
+func Middleware(authenticators ...Authenticator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			for _, a := range authenticators {
+				p, ok, err := a.Authenticate(c.Request())
+				if errors.Is(err, ErrInvalidCredentials) {
+					return unauthorized(c, ErrInvalidCredentials.Error())
+				}
+				if err != nil {
+					return err
+				}
+				if ok {
+					SetPrincipal(c, p)
+					if p.IsService() {
+						logging.With(c, "api_key_id", p.KeyID)
+					} else {
+						logging.With(c, "user_id", p.UserID)
+					}
+					return next(c)
+				}
+			}
+			return unauthorized(c, "missing credentials")
+		}
+	}
+}
+
+// RequireScope limits a route to API keys holding scope. Users pass
+// through to the handler's ownership checks.
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p, ok := PrincipalFrom(c)
+			if !ok {
+				return echo.ErrUnauthorized
+			}
+			if p.IsService() && !p.HasScope(scope) {
+				return echo.NewHTTPError(http.StatusForbidden, "API key is missing scope "+scope)
+			}
+			return next(c)
+		}
+	}
+}
+
+func unauthorized(c echo.Context, message string) error {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+	return echo.NewHTTPError(http.StatusUnauthorized, message)
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// minSecretLength is the shortest HS256 secret accepted, 256 bits.
+const minSecretLength = 32
+
+// Claims are the JWT claims read by the API. The subject is the user id.
+type Claims struct {
+	Roles []string `json:"roles,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// JWT verifies bearer tokens signed with a single key.
+type JWT struct {
+	method jwt.SigningMethod
+	key    any
+}
+
+func NewHS256(secret []byte) (*JWT, error) {
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("auth: HS256 secret must be at least %d bytes", minSecretLength)
+	}
+	return &JWT{method: jwt.SigningMethodHS256, key: secret}, nil
+}
+
+func NewRS256(key *rsa.PublicKey) *JWT {
+	return &JWT{method: jwt.SigningMethodRS256, key: key}
+}
+
+// LoadKeyFile reads the verification key from path. A PEM encoded RSA
+// public key selects RS256; any other content is used as an HS256 secret.
+func LoadKeyFile(path string) (*JWT, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %s: %w", path, err)
+		}
+		return NewRS256(key), nil
+	}
+	return NewHS256(bytes.TrimSpace(data))
+}
+
+// Parse verifies token and returns the caller it identifies. The token
+// must expire; iat and nbf are checked when present.
+func (j *JWT) Parse(token string) (Principal, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return j.key, nil
+	}, jwt.WithValidMethods([]string{j.method.Alg()}), jwt.WithExpirationRequired(), jwt.WithIssuedAt())
+	if err != nil {
+		return Principal{}, err
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil || userID <= 0 {
+		return Principal{}, errors.New("token subject must be a user id")
+	}
+	return Principal{UserID: userID, Roles: claims.Roles}, nil
+}
+
+// Authenticate implements Authenticator for "Authorization: Bearer" headers.
+func (j *JWT) Authenticate(r *http.Request) (Principal, bool, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return Principal{}, false, nil
+	}
+	p, err := j.Parse(token)
+	if err != nil {
+		return Principal{}, true, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+	return p, true, nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get(echo.HeaderAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,268 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, claims Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// registered returns claims for subject that are valid for the next hour.
+func registered(subject string) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+}
+
+func TestParse(t *testing.T) {
+	verifier, err := NewHS256(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("given valid token should return principal", func(t *testing.T) {
+		token := sign(t, jwt.SigningMethodHS256, secret, Claims{
+			Roles:            []string{RoleAdmin},
+			RegisteredClaims: registered("7"),
+		})
+
+		got, err := verifier.Parse(token)
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		expected := Principal{UserID: 7, Roles: []string{RoleAdmin}}
+		if !reflect.DeepEqual(expected, got) {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+		if !got.IsAdmin() {
+			t.Errorf("expected admin")
+		}
+	})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"given wrong secret should fail", sign(t, jwt.SigningMethodHS256, []byte("another-secret-another-secret-00"), Claims{RegisteredClaims: registered("7")})},
+		{"given expired token should fail", sign(t, jwt.SigningMethodHS256, secret, Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "7", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}})},
+		{"given token without expiry should fail", sign(t, jwt.SigningMethodHS256, secret, Claims{Roles: []string{RoleAdmin}, RegisteredClaims: jwt.RegisteredClaims{Subject: "1"}})},
+		{"given token issued in the future should fail", sign(t, jwt.SigningMethodHS256, secret, Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "7", IssuedAt: jwt.NewNumericDate(time.Now().Add(time.Hour)), ExpiresAt: jwt.NewNumericDate(time.Now().Add(2 * time.Hour))}})},
+		{"given token not valid yet should fail", sign(t, jwt.SigningMethodHS256, secret, Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "7", NotBefore: jwt.NewNumericDate(time.Now().Add(time.Hour)), ExpiresAt: jwt.NewNumericDate(time.Now().Add(2 * time.Hour))}})},
+		{"given non numeric subject should fail", sign(t, jwt.SigningMethodHS256, secret, Claims{RegisteredClaims: registered("alice")})},
+		{"given unsigned token should fail", sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, Claims{RegisteredClaims: registered("7")})},
+		{"given garbage should fail", "not-a-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifier.Parse(tt.token); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("given RSA public key should verify RS256 tokens", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, "jwt.pub")
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		verifier, err := LoadKeyFile(path)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		token := sign(t, jwt.SigningMethodRS256, key, Claims{RegisteredClaims: registered("3")})
+		if p, err := verifier.Parse(token); err != nil || p.UserID != 3 {
+			t.Errorf("expected user 3, got %v and %v", p, err)
+		}
+		// an HS256 token signed with the public key must not be accepted
+		hs := sign(t, jwt.SigningMethodHS256, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), Claims{RegisteredClaims: registered("3")})
+		if _, err := verifier.Parse(hs); err == nil {
+			t.Errorf("expected algorithm confusion to be rejected")
+		}
+	})
+
+	t.Run("given short secret should fail", func(t *testing.T) {
+		path := filepath.Join(dir, "short.key")
+		if err := os.WriteFile(path, []byte("short\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadKeyFile(path); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	verifier, err := NewHS256(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Principal
+	handler := Middleware(verifier)(func(c echo.Context) error {
+		got, _ = PrincipalFrom(c)
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	t.Run("given missing token should return 401", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+		err := handler(c)
+
+		he, ok := err.(*echo.HTTPError)
+		if !ok || he.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %v", err)
+		}
+		if rec.Header().Get(echo.HeaderWWWAuthenticate) != "Bearer" {
+			t.Errorf("expected WWW-Authenticate challenge")
+		}
+	})
+
+	t.Run("given expired token should return 401", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+sign(t, jwt.SigningMethodHS256, secret, Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "5", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}}))
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		err := handler(c)
+
+		if he, ok := err.(*echo.HTTPError); !ok || he.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %v", err)
+		}
+	})
+
+	t.Run("given valid token should set principal", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+sign(t, jwt.SigningMethodHS256, secret, Claims{RegisteredClaims: registered("5")}))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		if err := handler(c); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got.UserID != 5 {
+			t.Errorf("expected user 5, got %v", got)
+		}
+	})
+}
+
+func TestRequireScope(t *testing.T) {
+	handler := RequireScope(ScopeWalletsWrite)(func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+	tests := []struct {
+		name      string
+		principal Principal
+		allowed   bool
+	}{
+		{"given user should pass to ownership checks", Principal{UserID: 1}, true},
+		{"given API key with scope should pass", Principal{KeyID: 1, Scopes: []string{ScopeWalletsRead, ScopeWalletsWrite}}, true},
+		{"given API key without scope should return 403", Principal{KeyID: 1, Scopes: []string{ScopeWalletsRead}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+			SetPrincipal(c, tt.principal)
+
+			err := handler(c)
+
+			if tt.allowed && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if he, ok := err.(*echo.HTTPError); !tt.allowed && (!ok || he.Code != http.StatusForbidden) {
+				t.Errorf("expected 403, got %v", err)
+			}
+		})
+	}
+}
+
+type stubAuthenticator struct {
+	principal Principal
+	ok        bool
+	err       error
+}
+
+func (s stubAuthenticator) Authenticate(*http.Request) (Principal, bool, error) {
+	return s.principal, s.ok, s.err
+}
+
+func TestMiddlewareChain(t *testing.T) {
+	run := func(authenticators ...Authenticator) (Principal, error) {
+		var got Principal
+		handler := Middleware(authenticators...)(func(c echo.Context) error {
+			got, _ = PrincipalFrom(c)
+			return nil
+		})
+		e := echo.New()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+		return got, handler(c)
+	}
+
+	t.Run("given first authenticator has no credentials should try the next", func(t *testing.T) {
+		got, err := run(stubAuthenticator{}, stubAuthenticator{principal: Principal{KeyID: 4}, ok: true})
+
+		if err != nil || got.KeyID != 4 {
+			t.Errorf("expected key 4, got %v and %v", got, err)
+		}
+	})
+
+	t.Run("given invalid credentials should stop with 401", func(t *testing.T) {
+		_, err := run(stubAuthenticator{ok: true, err: fmt.Errorf("%w: %w", ErrInvalidCredentials, jwt.ErrTokenExpired)}, stubAuthenticator{principal: Principal{KeyID: 4}, ok: true})
+
+		if he, ok := err.(*echo.HTTPError); !ok || he.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %v", err)
+		}
+	})
+
+	t.Run("given credentials could not be checked should return the error", func(t *testing.T) {
+		outage := errors.New("connection refused")
+
+		_, err := run(stubAuthenticator{ok: true, err: outage})
+
+		if !errors.Is(err, outage) {
+			t.Errorf("expected the store error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,288 @@
+// Package config loads server settings from defaults, an optional YAML
+// file, environment variables and command-line flags, each overriding the
+// one before.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server   Server   `yaml:"server"`
+	Database Database `yaml:"database"`
+	Auth     Auth     `yaml:"auth"`
+	FX       FX       `yaml:"fx"`
+	Log      Log      `yaml:"log"`
+	Tracing  Tracing  `yaml:"tracing"`
+	Features Features `yaml:"features"`
+}
+
+type Server struct {
+	Addr         string        `yaml:"addr"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout bounds how long in-flight requests may drain after
+	// SIGINT or SIGTERM.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type Database struct {
+	// Driver is DriverPostgres or DriverMemory. The in-memory store needs
+	// no database and loses its data on exit.
+	Driver          string        `yaml:"driver"`
+	DSN             string        `yaml:"dsn"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+}
+
+type Auth struct {
+	// JWTKeyFile is a PEM RSA public key for RS256 or an HS256 secret.
+	JWTKeyFile string `yaml:"jwt_key_file"`
+}
+
+type FX struct {
+	// RatesFile is a JSON rate table; without it only same-currency
+	// transfers are allowed.
+	RatesFile string `yaml:"rates_file"`
+}
+
+type Log struct {
+	Level string `yaml:"level"`
+}
+
+type Tracing struct {
+	// Exporter is TracingNone, TracingStdout or TracingOTLP. The OTLP
+	// exporter is configured with the standard OTEL_EXPORTER_OTLP_*
+	// variables.
+	Exporter string `yaml:"exporter"`
+	// File receives the stdout exporter's spans instead of stdout.
+	File string `yaml:"file"`
+}
+
+type Features struct {
+	// AutoMigrate applies pending migrations on startup.
+	AutoMigrate bool `yaml:"auto_migrate"`
+	// Seed loads sample data into an empty database.
+	Seed    bool `yaml:"seed"`
+	Swagger bool `yaml:"swagger"`
+	// Metrics serves Prometheus metrics on /metrics.
+	Metrics bool `yaml:"metrics"`
+}
+
+const (
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory"
+)
+
+const (
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingOTLP   = "otlp"
+)
+
+var LogLevels = []string{"debug", "info", "warn", "error", "off"}
+
+// Default is the configuration used for anything left unset.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:         ":1323",
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 30 * time.Second,
+			IdleTimeout:  2 * time.Minute,
+			// below the usual 30s orchestrator grace period before SIGKILL
+			ShutdownTimeout: 25 * time.Second,
+		},
+		Database: Database{
+			Driver:          DriverPostgres,
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		Log:      Log{Level: "info"},
+		Tracing:  Tracing{Exporter: TracingNone},
+		Features: Features{AutoMigrate: true, Swagger: true, Metrics: true},
+	}
+}
+
+// setting ties one field to its flag and environment variable.
+type setting struct {
+	flag, env, usage string
+	field            func(c *Config) any
+}
+
+var settings = []setting{
+	{"addr", "ADDR", "listen address", func(c *Config) any { return &c.Server.Addr }},
+	{"read-timeout", "READ_TIMEOUT", "maximum duration for reading a request", func(c *Config) any { return &c.Server.ReadTimeout }},
+	{"write-timeout", "WRITE_TIMEOUT", "maximum duration for writing a response", func(c *Config) any { return &c.Server.WriteTimeout }},
+	{"idle-timeout", "IDLE_TIMEOUT", "how long keep-alive connections stay open", func(c *Config) any { return &c.Server.IdleTimeout }},
+	{"shutdown-timeout", "SHUTDOWN_TIMEOUT", "how long in-flight requests may finish after SIGINT or SIGTERM", func(c *Config) any { return &c.Server.ShutdownTimeout }},
+	{"db-driver", "DB_DRIVER", "storage backend: postgres or memory", func(c *Config) any { return &c.Database.Driver }},
+	{"db-conn", "DB_CONN", "Postgres connection string", func(c *Config) any { return &c.Database.DSN }},
+	{"db-max-open-conns", "DB_MAX_OPEN_CONNS", "maximum open database connections, 0 for unlimited", func(c *Config) any { return &c.Database.MaxOpenConns }},
+	{"db-max-idle-conns", "DB_MAX_IDLE_CONNS", "maximum idle database connections", func(c *Config) any { return &c.Database.MaxIdleConns }},
+	{"db-conn-max-lifetime", "DB_CONN_MAX_LIFETIME", "maximum time a database connection is reused, 0 for forever", func(c *Config) any { return &c.Database.ConnMaxLifetime }},
+	{"jwt-key-file", "JWT_KEY_FILE", "JWT verification key file", func(c *Config) any { return &c.Auth.JWTKeyFile }},
+	{"fx-rates-file", "FX_RATES_FILE", "exchange rate file", func(c *Config) any { return &c.FX.RatesFile }},
+	{"log-level", "LOG_LEVEL", "debug, info, warn, error or off", func(c *Config) any { return &c.Log.Level }},
+	{"tracing-exporter", "TRACING_EXPORTER", "span exporter: none, stdout or otlp", func(c *Config) any { return &c.Tracing.Exporter }},
+	{"tracing-file", "TRACING_FILE", "file for the stdout span exporter", func(c *Config) any { return &c.Tracing.File }},
+	{"auto-migrate", "DB_AUTO_MIGRATE", "apply pending migrations on startup", func(c *Config) any { return &c.Features.AutoMigrate }},
+	{"seed", "DB_SEED", "load sample data into an empty database", func(c *Config) any { return &c.Features.Seed }},
+	{"swagger", "SWAGGER_ENABLED", "serve the Swagger UI", func(c *Config) any { return &c.Features.Swagger }},
+	{"metrics", "METRICS_ENABLED", "serve Prometheus metrics on /metrics", func(c *Config) any { return &c.Features.Metrics }},
+}
+
+// Load builds the configuration from args (without the program name) and
+// getenv. The file comes from -config or CONFIG_FILE. It returns the
+// arguments left after the flags. The result is not validated: the server
+// needs Validate, commands that only use the database Database.Validate.
+func Load(args []string, getenv func(string) string) (Config, []string, error) {
+	cfg := Default()
+
+	flags := flag.NewFlagSet("app", flag.ContinueOnError)
+	file := flags.String("config", getenv("CONFIG_FILE"), "YAML config file")
+	given := map[string]string{}
+	for _, s := range settings {
+		flags.Var(flagValue{given: given, name: s.flag, isBool: isBool(s.field(&cfg))}, s.flag, s.usage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+
+	if *file != "" {
+		if err := loadFile(&cfg, *file); err != nil {
+			return cfg, nil, err
+		}
+	}
+	for _, s := range settings {
+		if value := getenv(s.env); value != "" {
+			if err := set(s.field(&cfg), value); err != nil {
+				return cfg, nil, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+	for _, s := range settings {
+		if value, ok := given[s.flag]; ok {
+			if err := set(s.field(&cfg), value); err != nil {
+				return cfg, nil, fmt.Errorf("-%s: %w", s.flag, err)
+			}
+		}
+	}
+	return cfg, flags.Args(), nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	check(c.Server.Addr != "", "server.addr is required")
+	check(c.Server.ReadTimeout >= 0, "server.read_timeout must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout must not be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idle_timeout must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	if err := c.Database.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	check(c.Auth.JWTKeyFile != "", "auth.jwt_key_file is required")
+	check(validLogLevel(c.Log.Level), "log.level must be one of %v", LogLevels)
+	check(c.Tracing.Exporter == TracingNone || c.Tracing.Exporter == TracingStdout || c.Tracing.Exporter == TracingOTLP,
+		"tracing.exporter must be %s, %s or %s", TracingNone, TracingStdout, TracingOTLP)
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid database setting at once.
+func (d Database) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	check(d.Driver == DriverPostgres || d.Driver == DriverMemory, "database.driver must be %s or %s", DriverPostgres, DriverMemory)
+	check(d.DSN != "" || d.Driver != DriverPostgres, "database.dsn is required")
+	check(d.MaxOpenConns >= 0, "database.max_open_conns must not be negative")
+	check(d.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	check(d.MaxOpenConns == 0 || d.MaxIdleConns <= d.MaxOpenConns,
+		"database.max_idle_conns must not exceed database.max_open_conns")
+	check(d.ConnMaxLifetime >= 0, "database.conn_max_lifetime must not be negative")
+	return errors.Join(errs...)
+}
+
+func validLogLevel(level string) bool {
+	for _, l := range LogLevels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+func set(field any, value string) error {
+	var err error
+	switch f := field.(type) {
+	case *string:
+		*f = value
+	case *int:
+		*f, err = strconv.Atoi(value)
+	case *bool:
+		*f, err = strconv.ParseBool(value)
+	case *time.Duration:
+		*f, err = time.ParseDuration(value)
+	default:
+		panic(fmt.Sprintf("config: unsupported field type %s", reflect.TypeOf(field)))
+	}
+	return err
+}
+
+func isBool(field any) bool {
+	_, ok := field.(*bool)
+	return ok
+}
+
+// flagValue records a flag so it can be applied after the file and the
+// environment.
+type flagValue struct {
+	given  map[string]string
+	name   string
+	isBool bool
+}
+
+func (v flagValue) String() string { return "" }
+
+func (v flagValue) Set(value string) error {
+	v.given[v.name] = value
+	return nil
+}
+
+func (v flagValue) IsBoolFlag() bool { return v.isBool }
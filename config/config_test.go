@@ -0,0 +1,162 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func TestLoad(t *testing.T) {
+	required := map[string]string{"DB_CONN": "host=db", "JWT_KEY_FILE": "jwt.key"}
+
+	t.Run("given only required settings should use defaults", func(t *testing.T) {
+		cfg, args, err := Load(nil, env(required))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Default()
+		want.Database.DSN = "host=db"
+		want.Auth.JWTKeyFile = "jwt.key"
+		if cfg != want || len(args) != 0 {
+			t.Errorf("expected %+v, got %+v and %v", want, cfg, args)
+		}
+	})
+
+	t.Run("given file, env and flags should apply them in that order", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		os.WriteFile(path, []byte(`
+server:
+  addr: ":8080"
+  read_timeout: 1s
+database:
+  dsn: "host=file"
+  max_open_conns: 10
+  max_idle_conns: 5
+auth:
+  jwt_key_file: file.key
+log:
+  level: debug
+features:
+  swagger: false
+`), 0o600)
+
+		cfg, args, err := Load(
+			[]string{"-config", path, "-addr", ":9090", "-seed", "migrate", "up"},
+			env(map[string]string{"ADDR": ":7070", "DB_CONN": "host=env", "LOG_LEVEL": "warn"}),
+		)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Server.Addr != ":9090" || cfg.Database.DSN != "host=env" || cfg.Log.Level != "warn" {
+			t.Errorf("expected flag addr and env dsn and level, got %+v", cfg)
+		}
+		if cfg.Server.ReadTimeout != time.Second || cfg.Database.MaxOpenConns != 10 || cfg.Auth.JWTKeyFile != "file.key" || cfg.Features.Swagger {
+			t.Errorf("expected file settings, got %+v", cfg)
+		}
+		if !cfg.Features.Seed || !cfg.Features.AutoMigrate {
+			t.Errorf("expected seed flag and default auto migrate, got %+v", cfg.Features)
+		}
+		if strings.Join(args, " ") != "migrate up" {
+			t.Errorf("expected remaining args migrate up, got %v", args)
+		}
+	})
+
+	t.Run("given CONFIG_FILE should load it", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		os.WriteFile(path, []byte("database:\n  dsn: host=file\nauth:\n  jwt_key_file: file.key\n"), 0o600)
+
+		cfg, _, err := Load(nil, env(map[string]string{"CONFIG_FILE": path}))
+
+		if err != nil || cfg.Database.DSN != "host=file" {
+			t.Errorf("expected dsn from file, got %+v and %v", cfg, err)
+		}
+	})
+
+	t.Run("given unknown file key should return error", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		os.WriteFile(path, []byte("server:\n  port: 1323\n"), 0o600)
+
+		if _, _, err := Load([]string{"-config", path}, env(required)); err == nil {
+			t.Error("expected error for unknown key")
+		}
+	})
+
+	t.Run("given malformed values should return error", func(t *testing.T) {
+		cases := map[string]struct {
+			args []string
+			env  map[string]string
+		}{
+			"env duration": {nil, map[string]string{"READ_TIMEOUT": "soon"}},
+			"env bool":     {nil, map[string]string{"DB_SEED": "maybe"}},
+			"flag int":     {[]string{"-db-max-open-conns", "many"}, nil},
+			"unknown flag": {[]string{"-port", "1323"}, nil},
+		}
+		for name, tc := range cases {
+			values := map[string]string{}
+			for k, v := range required {
+				values[k] = v
+			}
+			for k, v := range tc.env {
+				values[k] = v
+			}
+			if _, _, err := Load(tc.args, env(values)); err == nil {
+				t.Errorf("%s: expected error", name)
+			}
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Addr = ""
+	cfg.Server.ShutdownTimeout = 0
+	cfg.Database.MaxOpenConns = 5
+	cfg.Database.MaxIdleConns = 10
+	cfg.Log.Level = "verbose"
+	cfg.Tracing.Exporter = "jaeger"
+
+	err := cfg.Validate()
+
+	for _, want := range []string{"server.addr", "server.shutdown_timeout", "database.dsn", "database.max_idle_conns", "auth.jwt_key_file", "log.level", "tracing.exporter"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %s in error, got %v", want, err)
+		}
+	}
+}
+
+func TestValidateDatabase(t *testing.T) {
+	cfg := Default()
+	cfg.Database.DSN = "host=db"
+
+	if err := cfg.Database.Validate(); err != nil {
+		t.Errorf("expected database settings without a JWT key to be valid, got %v", err)
+	}
+
+	cfg.Database.MaxOpenConns = -1
+	if err := cfg.Database.Validate(); err == nil || !strings.Contains(err.Error(), "database.max_open_conns") {
+		t.Errorf("expected database.max_open_conns in error, got %v", err)
+	}
+}
+
+func TestValidateDriver(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.JWTKeyFile = "jwt.key"
+
+	cfg.Database.Driver = DriverMemory
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected memory driver without dsn to be valid, got %v", err)
+	}
+
+	cfg.Database.Driver = "sqlite"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "database.driver") {
+		t.Errorf("expected database.driver in error, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package fx
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+// Static is an in-memory wallet.RateProvider. Rates are keyed "FROM/TO";
+// the inverse direction is derived when it is not listed.
+type Static struct {
+	mu    sync.RWMutex
+	rates map[string]*big.Rat
+}
+
+// NewStatic builds a provider from decimal rate strings such as
+// {"USD/THB": "36.25"}.
+func NewStatic(rates map[string]string) (*Static, error) {
+	s := &Static{rates: map[string]*big.Rat{}}
+	for pair, value := range rates {
+		if err := s.Set(pair, value); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// LoadFile reads a JSON object of "FROM/TO": "rate" pairs.
+func LoadFile(path string) (*Static, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rates map[string]string
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("fx: %s: %w", path, err)
+	}
+	return NewStatic(rates)
+}
+
+// Set adds or replaces the rate for a "FROM/TO" pair.
+func (s *Static) Set(pair, value string) error {
+	from, to, ok := strings.Cut(strings.ToUpper(pair), "/")
+	if !ok || !wallet.ValidCurrency(from) || !wallet.ValidCurrency(to) {
+		return fmt.Errorf("fx: invalid currency pair %q", pair)
+	}
+	rate, ok := new(big.Rat).SetString(value)
+	if !ok || rate.Sign() <= 0 {
+		return fmt.Errorf("fx: invalid rate %q for %s", value, pair)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rates[from+"/"+to] = rate
+	return nil
+}
+
+func (s *Static) Rate(from, to string) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if rate, ok := s.rates[from+"/"+to]; ok {
+		return new(big.Rat).Set(rate), nil
+	}
+	if rate, ok := s.rates[to+"/"+from]; ok {
+		return new(big.Rat).Inv(rate), nil
+	}
+	return nil, wallet.ErrRateUnavailable
+}
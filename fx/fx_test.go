@@ -0,0 +1,70 @@
+package fx
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+func TestStaticRate(t *testing.T) {
+	rates, err := NewStatic(map[string]string{"USD/THB": "36.25"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("given listed pair should return the rate", func(t *testing.T) {
+		rate, err := rates.Rate("USD", "THB")
+		if err != nil || rate.Cmp(big.NewRat(145, 4)) != 0 {
+			t.Errorf("expected 36.25, got %v and %v", rate, err)
+		}
+	})
+
+	t.Run("given inverse pair should derive the rate", func(t *testing.T) {
+		rate, err := rates.Rate("THB", "USD")
+		if err != nil || rate.Cmp(big.NewRat(4, 145)) != 0 {
+			t.Errorf("expected 4/145, got %v and %v", rate, err)
+		}
+	})
+
+	t.Run("given unknown pair should return rate unavailable", func(t *testing.T) {
+		if _, err := rates.Rate("USD", "JPY"); !errors.Is(err, wallet.ErrRateUnavailable) {
+			t.Errorf("expected ErrRateUnavailable, got %v", err)
+		}
+	})
+
+	t.Run("given invalid currency should fail", func(t *testing.T) {
+		if _, err := NewStatic(map[string]string{"USD/XXX": "1"}); err == nil {
+			t.Errorf("expected error for unknown currency")
+		}
+	})
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(path, []byte(`{"USD/THB": "36.25"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rates, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	converted, err := wallet.MustParseMoney("10").Convert(mustRate(t, rates, "USD", "THB"), 2)
+	if err != nil || converted != wallet.MustParseMoney("362.5") {
+		t.Errorf("expected 362.50, got %s, %v", converted, err)
+	}
+}
+
+func mustRate(t *testing.T, rates *Static, from, to string) *big.Rat {
+	t.Helper()
+	rate, err := rates.Rate(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rate
+}
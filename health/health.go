@@ -0,0 +1,193 @@
+// Package health serves the liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Version is the release version, set at build time with
+// -ldflags "-X github.com/KKGo-Software-engineering/fun-exercise-api/health.Version=1.2.3".
+var Version = "dev"
+
+const (
+	StatusOK           = "ok"
+	StatusStarting     = "starting"
+	StatusUnavailable  = "unavailable"
+	StatusShuttingDown = "shutting_down"
+)
+
+// pingTimeout keeps a hung database from hanging the probe.
+const pingTimeout = 2 * time.Second
+
+// Database is satisfied by *sql.DB. A nil Database skips the check.
+type Database interface {
+	PingContext(ctx context.Context) error
+	Stats() sql.DBStats
+}
+
+type state int32
+
+const (
+	starting state = iota
+	ready
+	shuttingDown
+)
+
+type Handler struct {
+	db      Database
+	state   atomic.Int32
+	started time.Time
+	build   Build
+}
+
+type Build struct {
+	Version   string `json:"version" example:"1.2.3"`
+	Revision  string `json:"revision,omitempty" example:"52ad161"`
+	Time      string `json:"time,omitempty" example:"2024-03-01T10:00:00Z"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version" example:"go1.22.1"`
+}
+
+type Status struct {
+	Status string           `json:"status" example:"ok"`
+	Uptime string           `json:"uptime" example:"1h2m3s"`
+	Build  Build            `json:"build"`
+	Checks map[string]Check `json:"checks,omitempty"`
+}
+
+type Check struct {
+	Status  string `json:"status" example:"ok"`
+	Error   string `json:"error,omitempty"`
+	Latency string `json:"latency,omitempty" example:"1.2ms"`
+	Pool    *Pool  `json:"pool,omitempty"`
+}
+
+// Pool is a snapshot of the database connection pool.
+type Pool struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+}
+
+// New starts in the starting state; call Ready once the database is
+// migrated and the server can take traffic.
+func New(db Database) *Handler {
+	return &Handler{db: db, started: time.Now(), build: readBuild()}
+}
+
+func (h *Handler) Ready() {
+	h.state.Store(int32(ready))
+}
+
+// ShuttingDown fails readiness so load balancers stop sending traffic
+// while in-flight requests drain.
+func (h *Handler) ShuttingDown() {
+	h.state.Store(int32(shuttingDown))
+}
+
+// LivenessHandler
+//
+//	@Summary		Liveness probe
+//	@Description	Reports that the process is up. It does not check dependencies, so a database outage does not get the process restarted.
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	Status
+//	@Router			/healthz [get]
+func (h *Handler) LivenessHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.status(StatusOK))
+}
+
+// ReadinessHandler
+//
+//	@Summary		Readiness probe
+//	@Description	Reports whether the server can take traffic: startup has finished, it is not shutting down and Postgres answers a ping.
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	Status
+//	@Failure		503	{object}	Status
+//	@Router			/readyz [get]
+func (h *Handler) ReadinessHandler(c echo.Context) error {
+	status := h.status(StatusOK)
+	if h.db != nil {
+		database := h.checkDatabase(c.Request().Context())
+		status.Checks = map[string]Check{"postgres": database}
+		if database.Status != StatusOK {
+			status.Status = StatusUnavailable
+		}
+	}
+
+	switch state(h.state.Load()) {
+	case starting:
+		status.Status = StatusStarting
+	case shuttingDown:
+		status.Status = StatusShuttingDown
+	}
+	if status.Status != StatusOK {
+		return c.JSON(http.StatusServiceUnavailable, status)
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+func (h *Handler) checkDatabase(ctx context.Context) Check {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := h.db.PingContext(ctx)
+	stats := h.db.Stats()
+	check := Check{
+		Status:  StatusOK,
+		Latency: time.Since(start).String(),
+		Pool: &Pool{
+			MaxOpenConnections: stats.MaxOpenConnections,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			WaitCount:          stats.WaitCount,
+			WaitDuration:       stats.WaitDuration.String(),
+		},
+	}
+	if err != nil {
+		check.Status = StatusUnavailable
+		check.Error = err.Error()
+	}
+	return check
+}
+
+func (h *Handler) status(s string) Status {
+	return Status{
+		Status: s,
+		Uptime: time.Since(h.started).Round(time.Second).String(),
+		Build:  h.build,
+	}
+}
+
+func readBuild() Build {
+	build := Build{Version: Version, GoVersion: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return build
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			build.Revision = s.Value
+		case "vcs.time":
+			build.Time = s.Value
+		case "vcs.modified":
+			build.Modified = s.Value == "true"
+		}
+	}
+	return build
+}
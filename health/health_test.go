@@ -0,0 +1,113 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type StubDatabase struct {
+	err error
+}
+
+func (s StubDatabase) PingContext(ctx context.Context) error {
+	return s.err
+}
+
+func (s StubDatabase) Stats() sql.DBStats {
+	return sql.DBStats{MaxOpenConnections: 25, OpenConnections: 3, InUse: 1, Idle: 2}
+}
+
+func probe(t *testing.T, handler echo.HandlerFunc) (int, Status) {
+	t.Helper()
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	if err := handler(c); err != nil {
+		t.Fatal(err)
+	}
+	var status Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("expected status, got %s", rec.Body.String())
+	}
+	return rec.Code, status
+}
+
+func TestLiveness(t *testing.T) {
+	t.Run("given database down should still be alive", func(t *testing.T) {
+		h := New(StubDatabase{err: errors.New("connection refused")})
+
+		code, status := probe(t, h.LivenessHandler)
+
+		if code != http.StatusOK || status.Status != StatusOK || status.Build.Version != Version || status.Build.GoVersion == "" {
+			t.Errorf("expected 200 ok with build info, got %d and %+v", code, status)
+		}
+	})
+}
+
+func TestReadiness(t *testing.T) {
+	t.Run("given startup not finished should return 503 starting", func(t *testing.T) {
+		h := New(StubDatabase{})
+
+		code, status := probe(t, h.ReadinessHandler)
+
+		if code != http.StatusServiceUnavailable || status.Status != StatusStarting {
+			t.Errorf("expected 503 starting, got %d and %+v", code, status)
+		}
+	})
+
+	t.Run("given ready and database reachable should return 200 with pool stats", func(t *testing.T) {
+		h := New(StubDatabase{})
+		h.Ready()
+
+		code, status := probe(t, h.ReadinessHandler)
+
+		if code != http.StatusOK || status.Status != StatusOK {
+			t.Fatalf("expected 200 ok, got %d and %+v", code, status)
+		}
+		postgres := status.Checks["postgres"]
+		if postgres.Status != StatusOK || postgres.Pool == nil || postgres.Pool.OpenConnections != 3 {
+			t.Errorf("expected postgres check with pool stats, got %+v", postgres)
+		}
+	})
+
+	t.Run("given database unreachable should return 503 unavailable", func(t *testing.T) {
+		h := New(StubDatabase{err: errors.New("connection refused")})
+		h.Ready()
+
+		code, status := probe(t, h.ReadinessHandler)
+
+		if code != http.StatusServiceUnavailable || status.Status != StatusUnavailable || status.Checks["postgres"].Error != "connection refused" {
+			t.Errorf("expected 503 unavailable, got %d and %+v", code, status)
+		}
+	})
+
+	t.Run("given no database should only report the state", func(t *testing.T) {
+		h := New(nil)
+		h.Ready()
+
+		code, status := probe(t, h.ReadinessHandler)
+
+		if code != http.StatusOK || status.Status != StatusOK || status.Checks != nil {
+			t.Errorf("expected 200 ok without checks, got %d and %+v", code, status)
+		}
+	})
+
+	t.Run("given shutting down should return 503", func(t *testing.T) {
+		h := New(StubDatabase{})
+		h.Ready()
+		h.ShuttingDown()
+
+		code, status := probe(t, h.ReadinessHandler)
+
+		if code != http.StatusServiceUnavailable || status.Status != StatusShuttingDown {
+			t.Errorf("expected 503 shutting down, got %d and %+v", code, status)
+		}
+	})
+}
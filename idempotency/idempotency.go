@@ -0,0 +1,201 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	HeaderReplayed       = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+	// maxBodySize bounds the request body read into memory for hashing.
+	maxBodySize = 1 << 20
+)
+
+// TTL is how long a stored response is replayed before its key may be reused.
+const TTL = 24 * time.Hour
+
+// Lease is how long a reserved key stays in progress. A request that dies
+// before its response is recorded leaves the key pending; once the lease
+// has run out a retry may take it over. It is well above the server's
+// write timeout, so a request still being served keeps its key.
+const Lease = 2 * time.Minute
+
+// replayedHeaders are the response headers stored alongside the body.
+var replayedHeaders = []string{echo.HeaderContentType, "ETag"}
+
+type Record struct {
+	Key string
+	// Token identifies one reservation of Key. Completing or releasing a
+	// key only applies while the reservation holding the token still owns
+	// it, so a request that outlived its lease cannot touch a takeover.
+	Token       string
+	Method      string
+	Path        string
+	RequestHash string
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+	Completed   bool
+	CreatedAt   time.Time
+}
+
+type Storer interface {
+	// ReserveIdempotencyKey stores a pending record for the key. When the key
+	// is already taken it returns the existing record and false.
+	ReserveIdempotencyKey(ctx context.Context, record Record) (Record, bool, error)
+	// CompleteIdempotencyKey stores the response of the reservation
+	// matching record.Key and record.Token.
+	CompleteIdempotencyKey(ctx context.Context, record Record) error
+	// ReleaseIdempotencyKey drops the pending reservation matching
+	// record.Key and record.Token.
+	ReleaseIdempotencyKey(ctx context.Context, record Record) error
+}
+
+// Option configures Middleware.
+type Option func(*options)
+
+type options struct {
+	scope func(c echo.Context) string
+}
+
+// WithScope namespaces keys by the value scope returns for a request, such
+// as the authenticated user, so callers cannot replay each other's responses.
+func WithScope(scope func(c echo.Context) string) Option {
+	return func(o *options) {
+		o.scope = scope
+	}
+}
+
+type recorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Middleware replays the stored response for requests that repeat an
+// Idempotency-Key. Requests without the header pass straight through and
+// bodies over 1MB are rejected with 413.
+// Server errors release the key so the client can retry. Its own errors
+// are returned for the HTTPErrorHandler to render.
+func Middleware(store Storer, opts ...Option) echo.MiddlewareFunc {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxKeyLength {
+				return echo.NewHTTPError(http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+			}
+
+			req := c.Request()
+			body, err := io.ReadAll(http.MaxBytesReader(c.Response(), req.Body, maxBodySize))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "request body must be at most 1MB")
+			}
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "request body could not be read")
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			if o.scope != nil {
+				key = o.scope(c) + ":" + key
+			}
+
+			token, err := newToken()
+			if err != nil {
+				return err
+			}
+			record := Record{
+				Key:         key,
+				Token:       token,
+				Method:      req.Method,
+				Path:        req.URL.Path,
+				RequestHash: hash(req.Method, req.URL.Path, body),
+			}
+			existing, reserved, err := store.ReserveIdempotencyKey(req.Context(), record)
+			if err != nil {
+				return err
+			}
+			if !reserved {
+				return replay(c, record, existing)
+			}
+
+			rec := &recorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = rec
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			// the response is already written, so record it even if the
+			// client has gone away
+			ctx := context.WithoutCancel(req.Context())
+			if c.Response().Status >= http.StatusInternalServerError {
+				return store.ReleaseIdempotencyKey(ctx, record)
+			}
+			record.StatusCode = c.Response().Status
+			record.Header = http.Header{}
+			for _, name := range replayedHeaders {
+				if v := c.Response().Header().Get(name); v != "" {
+					record.Header.Set(name, v)
+				}
+			}
+			record.Body = rec.body.Bytes()
+			record.Completed = true
+			return store.CompleteIdempotencyKey(ctx, record)
+		}
+	}
+}
+
+func replay(c echo.Context, record, existing Record) error {
+	if existing.RequestHash != record.RequestHash {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+	}
+	if !existing.Completed {
+		return echo.NewHTTPError(http.StatusConflict, "a request with this Idempotency-Key is still in progress")
+	}
+	for name, values := range existing.Header {
+		for _, v := range values {
+			c.Response().Header().Add(name, v)
+		}
+	}
+	c.Response().Header().Set(HeaderReplayed, "true")
+	c.Response().WriteHeader(existing.StatusCode)
+	_, err := c.Response().Write(existing.Body)
+	return err
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,204 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type StubStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+func NewStubStore() *StubStore {
+	return &StubStore{records: map[string]Record{}}
+}
+
+func (s *StubStore) ReserveIdempotencyKey(ctx context.Context, record Record) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[record.Key]; ok {
+		return existing, false, nil
+	}
+	s.records[record.Key] = record
+	return record, true, nil
+}
+
+func (s *StubStore) CompleteIdempotencyKey(ctx context.Context, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.Key] = record
+	return nil
+}
+
+func (s *StubStore) ReleaseIdempotencyKey(ctx context.Context, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.records[record.Key].Token == record.Token {
+		delete(s.records, record.Key)
+	}
+	return nil
+}
+
+type FailingStore struct {
+	err error
+}
+
+func (s FailingStore) ReserveIdempotencyKey(ctx context.Context, record Record) (Record, bool, error) {
+	return Record{}, false, s.err
+}
+
+func (s FailingStore) CompleteIdempotencyKey(ctx context.Context, record Record) error {
+	return s.err
+}
+
+func (s FailingStore) ReleaseIdempotencyKey(ctx context.Context, record Record) error {
+	return s.err
+}
+
+func serve(e *echo.Echo, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	newServer := func(store Storer, status int) (*echo.Echo, *int) {
+		calls := 0
+		e := echo.New()
+		e.POST("/api/v1/wallets", func(c echo.Context) error {
+			calls++
+			c.Response().Header().Set("ETag", `"1"`)
+			return c.JSON(status, map[string]int{"id": calls})
+		}, Middleware(store))
+		return e, &calls
+	}
+
+	t.Run("given retried request with the same key should replay the first response", func(t *testing.T) {
+		e, calls := newServer(NewStubStore(), http.StatusCreated)
+
+		first := serve(e, "abc", `{"wallet_name": "a"}`)
+		second := serve(e, "abc", `{"wallet_name": "a"}`)
+
+		if *calls != 1 {
+			t.Errorf("expected handler to run once, ran %d times", *calls)
+		}
+		if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+			t.Errorf("expected replay of %d %s, got %d %s", first.Code, first.Body.String(), second.Code, second.Body.String())
+		}
+		if second.Header().Get(HeaderReplayed) != "true" || second.Header().Get("ETag") != `"1"` {
+			t.Errorf("expected replayed headers, got %v", second.Header())
+		}
+	})
+
+	t.Run("given same key with a different body should return 422", func(t *testing.T) {
+		e, calls := newServer(NewStubStore(), http.StatusCreated)
+
+		serve(e, "abc", `{"wallet_name": "a"}`)
+		rec := serve(e, "abc", `{"wallet_name": "b"}`)
+
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected 422, got %d and %s", rec.Code, rec.Body.String())
+		}
+		if *calls != 1 {
+			t.Errorf("expected handler to run once, ran %d times", *calls)
+		}
+	})
+
+	t.Run("given key still in progress should return 409", func(t *testing.T) {
+		store := NewStubStore()
+		e, _ := newServer(store, http.StatusCreated)
+		store.records["abc"] = Record{Key: "abc", RequestHash: hash(http.MethodPost, "/api/v1/wallets", []byte(`{}`))}
+
+		rec := serve(e, "abc", `{}`)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("expected 409, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given server error should release the key for retry", func(t *testing.T) {
+		e, calls := newServer(NewStubStore(), http.StatusInternalServerError)
+
+		serve(e, "abc", `{}`)
+		serve(e, "abc", `{}`)
+
+		if *calls != 2 {
+			t.Errorf("expected handler to run twice, ran %d times", *calls)
+		}
+	})
+
+	t.Run("given store failure should leave the response to the error handler", func(t *testing.T) {
+		e, calls := newServer(FailingStore{errors.New("pq: connection refused")}, http.StatusCreated)
+
+		rec := serve(e, "abc", `{}`)
+
+		if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "pq:") {
+			t.Errorf("expected 500 without details, got %d and %s", rec.Code, rec.Body.String())
+		}
+		if *calls != 0 {
+			t.Errorf("expected handler not to run, ran %d times", *calls)
+		}
+	})
+
+	t.Run("given body over the limit should return 413", func(t *testing.T) {
+		e, calls := newServer(NewStubStore(), http.StatusCreated)
+
+		rec := serve(e, "abc", `{"wallet_name": "`+strings.Repeat("a", maxBodySize)+`"}`)
+
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected 413, got %d", rec.Code)
+		}
+		if *calls != 0 {
+			t.Errorf("expected handler not to run, ran %d times", *calls)
+		}
+	})
+
+	t.Run("given scoped keys should not replay across callers", func(t *testing.T) {
+		calls := 0
+		e := echo.New()
+		e.POST("/api/v1/wallets", func(c echo.Context) error {
+			calls++
+			return c.JSON(http.StatusCreated, map[string]int{"id": calls})
+		}, Middleware(NewStubStore(), WithScope(func(c echo.Context) string {
+			return c.Request().Header.Get("X-User")
+		})))
+		send := func(user string) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets", strings.NewReader(`{}`))
+			req.Header.Set(HeaderIdempotencyKey, "abc")
+			req.Header.Set("X-User", user)
+			e.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		send("1")
+		send("2")
+		send("1")
+
+		if calls != 2 {
+			t.Errorf("expected handler to run once per caller, ran %d times", calls)
+		}
+	})
+
+	t.Run("given no key should always run the handler", func(t *testing.T) {
+		e, calls := newServer(NewStubStore(), http.StatusCreated)
+
+		serve(e, "", `{}`)
+		serve(e, "", `{}`)
+
+		if *calls != 2 {
+			t.Errorf("expected handler to run twice, ran %d times", *calls)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+// Package logging writes structured JSON logs with log/slog and
+// correlates every line logged during a request by its request ID.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxRequestIDLength bounds request IDs accepted from clients.
+const maxRequestIDLength = 128
+
+// levels maps config.LogLevels to slog levels. Nothing is logged above
+// error, so "off" silences everything.
+var levels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+	"off":   math.MaxInt,
+}
+
+// New returns a JSON logger that writes records at level and above.
+// Unknown levels fall back to info.
+func New(w io.Writer, level string) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: levels[level]}))
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries logger.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the request logger set by AccessLog, or
+// slog.Default() outside of a request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With adds attributes to the request logger, so they appear on the
+// access log line and on everything logged later in the request.
+func With(c echo.Context, args ...any) {
+	req := c.Request()
+	ctx := NewContext(req.Context(), FromContext(req.Context()).With(args...))
+	c.SetRequest(req.WithContext(ctx))
+}
+
+// RequestID keeps a well-formed X-Request-ID sent by the client, or
+// generates one, and echoes it in the response.
+func RequestID(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Request().Header.Get(echo.HeaderXRequestID)
+		if !validRequestID(id) {
+			id = newRequestID()
+			c.Request().Header.Set(echo.HeaderXRequestID, id)
+		}
+		c.Response().Header().Set(echo.HeaderXRequestID, id)
+		return next(c)
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Option configures AccessLog.
+type Option func(*options)
+
+type options struct {
+	quiet map[string]bool
+}
+
+// WithQuietPaths logs requests to the given routes at debug level, so
+// frequent callers such as health probes do not flood the log.
+func WithQuietPaths(paths ...string) Option {
+	return func(o *options) {
+		for _, p := range paths {
+			o.quiet[p] = true
+		}
+	}
+}
+
+// AccessLog puts a logger carrying the request ID, and the trace ID when
+// the request is traced, into the request context and logs one line per
+// request when it finishes. It runs after RequestID and the tracing
+// middleware.
+func AccessLog(logger *slog.Logger, opts ...Option) echo.MiddlewareFunc {
+	o := options{quiet: map[string]bool{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+			requestLogger := logger.With("request_id", req.Header.Get(echo.HeaderXRequestID))
+			if span := trace.SpanContextFromContext(req.Context()); span.IsValid() {
+				requestLogger = requestLogger.With("trace_id", span.TraceID().String())
+			}
+			c.SetRequest(req.WithContext(NewContext(req.Context(), requestLogger)))
+
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			res := c.Response()
+			level := slog.LevelInfo
+			switch {
+			case res.Status >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case o.quiet[c.Path()]:
+				level = slog.LevelDebug
+			}
+			FromContext(c.Request().Context()).LogAttrs(req.Context(), level, "request",
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.String("route", c.Path()),
+				slog.Int("status", res.Status),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.Int64("bytes", res.Size),
+				slog.String("remote_ip", c.RealIP()),
+			)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func serve(e *echo.Echo, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequestID(t *testing.T) {
+	e := echo.New()
+	e.Use(RequestID)
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Request().Header.Get(echo.HeaderXRequestID))
+	})
+
+	t.Run("given no request id should generate one", func(t *testing.T) {
+		rec := serve(e, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		id := rec.Header().Get(echo.HeaderXRequestID)
+		if len(id) != 32 || rec.Body.String() != id {
+			t.Errorf("expected a generated id seen by the handler, got %q and %q", id, rec.Body.String())
+		}
+	})
+
+	t.Run("given request id should keep it", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderXRequestID, "gateway-42")
+
+		rec := serve(e, req)
+
+		if got := rec.Header().Get(echo.HeaderXRequestID); got != "gateway-42" {
+			t.Errorf("expected gateway-42, got %q", got)
+		}
+	})
+
+	t.Run("given malformed request id should replace it", func(t *testing.T) {
+		for _, id := range []string{"has space", strings.Repeat("a", maxRequestIDLength+1)} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderXRequestID, id)
+
+			rec := serve(e, req)
+
+			if got := rec.Header().Get(echo.HeaderXRequestID); got == id || len(got) != 32 {
+				t.Errorf("expected %q to be replaced, got %q", id, got)
+			}
+		}
+	})
+}
+
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	e := echo.New()
+	e.Use(RequestID, AccessLog(New(&out, "info"), WithQuietPaths("/healthz")))
+	e.GET("/healthz", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.GET("/wallets/:id", func(c echo.Context) error {
+		With(c, "wallet_id", 7)
+		FromContext(c.Request().Context()).Info("loading wallet")
+		return errors.New("boom")
+	})
+	lines := func() []map[string]any {
+		var records []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			var record map[string]any
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("expected JSON lines, got %q", line)
+			}
+			records = append(records, record)
+		}
+		out.Reset()
+		return records
+	}
+
+	t.Run("given request should log it with its id and attributes", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/wallets/7", nil)
+		req.Header.Set(echo.HeaderXRequestID, "req-1")
+
+		rec := serve(e, req)
+
+		records := lines()
+		if rec.Code != http.StatusInternalServerError || len(records) != 2 {
+			t.Fatalf("expected a 500 and two log lines, got %d and %v", rec.Code, records)
+		}
+		if records[0]["msg"] != "loading wallet" || records[0]["request_id"] != "req-1" || records[0]["wallet_id"] != float64(7) {
+			t.Errorf("expected handler log with request and wallet id, got %v", records[0])
+		}
+		access := records[1]
+		want := map[string]any{"msg": "request", "level": "ERROR", "request_id": "req-1", "wallet_id": float64(7),
+			"method": "GET", "path": "/wallets/7", "route": "/wallets/:id", "status": float64(500)}
+		for k, v := range want {
+			if access[k] != v {
+				t.Errorf("expected %s=%v, got %v", k, v, access[k])
+			}
+		}
+		if _, ok := access["latency_ms"]; !ok {
+			t.Errorf("expected latency_ms, got %v", access)
+		}
+	})
+
+	t.Run("given traced request should log its trace id", func(t *testing.T) {
+		span := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35},
+			SpanID:     trace.SpanID{0x00, 0xf0, 0x67},
+			TraceFlags: trace.FlagsSampled,
+		})
+		req := httptest.NewRequest(http.MethodGet, "/wallets/7", nil)
+		req = req.WithContext(trace.ContextWithSpanContext(req.Context(), span))
+
+		serve(e, req)
+
+		for _, record := range lines() {
+			if record["trace_id"] != span.TraceID().String() {
+				t.Errorf("expected trace_id %s, got %v", span.TraceID(), record)
+			}
+		}
+	})
+
+	t.Run("given quiet path should log below info", func(t *testing.T) {
+		serve(e, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		if out.Len() != 0 {
+			t.Errorf("expected no info line for /healthz, got %s", out.String())
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	var out bytes.Buffer
+
+	New(&out, "off").Error("hidden")
+	New(&out, "warn").Info("hidden")
+	New(&out, "debug").Debug("shown")
+
+	if got := out.String(); strings.Contains(got, "hidden") || !strings.Contains(got, "shown") {
+		t.Errorf("expected only the debug line, got %s", got)
+	}
+}
@@ -1,9 +1,31 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/apikey"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/auth"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/config"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/fx"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/health"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/idempotency"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/logging"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/metrics"
 	"github.com/KKGo-Software-engineering/fun-exercise-api/postgres"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/tracing"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/user"
 	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
 	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 
 	_ "github.com/KKGo-Software-engineering/fun-exercise-api/docs"
 	echoSwagger "github.com/swaggo/echo-swagger"
@@ -13,20 +35,163 @@ import (
 // @version		1.0
 // @description	Sophisticated Wallet API
 // @host			localhost:1323
+//
+// @securityDefinitions.apikey	BearerAuth
+// @in							header
+// @name						Authorization
+// @description				JWT bearer token, for example "Bearer eyJ...". The subject is the user id; the "admin" role may act on any user's wallets.
+//
+// @securityDefinitions.apikey	ApiKeyAuth
+// @in							header
+// @name						X-API-Key
+// @description				API key for service callers, limited to the scopes it was issued with.
 func main() {
-	p, err := postgres.New()
+	cfg, args, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	// migrate only talks to the database, so it runs without the
+	// server settings such as the JWT key
+	validate := cfg.Validate
+	if len(args) > 0 && args[0] == "migrate" {
+		validate = cfg.Database.Validate
+	}
+	if err := validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logger := logging.New(os.Stdout, cfg.Log.Level)
+	slog.SetDefault(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, health.Version)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	store, err := openStore(cfg.Database)
 	if err != nil {
 		panic(err)
 	}
 
+	if len(args) > 0 && args[0] == "migrate" {
+		err := migrate(ctx, store, args[1:])
+		shutdownTracing(context.Background())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	e := echo.New()
-	e.GET("/swagger/*", echoSwagger.WrapHandler)
-
-	handler := wallet.New(p)
-	e.GET("/api/v1/wallets", handler.WalletHandler)
-	e.POST("/api/v1/wallets", handler.CreateWalletHandler)
-	e.PUT("/api/v1/wallets", handler.UpdateWalletHandler)
-	e.DELETE("/api/v1/users/:id/wallets", handler.DeleteWalletByUserIdHandler)
-	e.GET("/api/v1/users/:id/wallets", handler.WalletByUserIdHandler)
-	e.Logger.Fatal(e.Start(":1323"))
+	e.HideBanner = true
+	e.HidePort = true
+	e.Server.ReadTimeout = cfg.Server.ReadTimeout
+	e.Server.WriteTimeout = cfg.Server.WriteTimeout
+	e.Server.IdleTimeout = cfg.Server.IdleTimeout
+	e.HTTPErrorHandler = wallet.ErrorHandler
+	e.Validator = wallet.NewValidator()
+	e.Use(otelecho.Middleware(tracing.ServiceName), logging.RequestID, logging.AccessLog(logger, logging.WithQuietPaths("/healthz", "/readyz", "/metrics")))
+	if cfg.Features.Swagger {
+		e.GET("/swagger/*", echoSwagger.WrapHandler)
+	}
+	if cfg.Features.Metrics {
+		m := metrics.New()
+		m.RegisterTotals(store)
+		if p, ok := store.(*postgres.Postgres); ok {
+			m.RegisterDB(p.Db, "wallet")
+		}
+		e.Use(m.Middleware)
+		e.GET("/metrics", m.Handler())
+	}
+	var db health.Database
+	if p, ok := store.(*postgres.Postgres); ok {
+		db = p.Db
+	}
+	probes := health.New(db)
+	e.GET("/healthz", probes.LivenessHandler)
+	e.GET("/readyz", probes.ReadinessHandler)
+
+	rates, err := fx.NewStatic(nil)
+	if cfg.FX.RatesFile != "" {
+		rates, err = fx.LoadFile(cfg.FX.RatesFile)
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	jwt, err := auth.LoadKeyFile(cfg.Auth.JWTKeyFile)
+	if err != nil {
+		panic(err)
+	}
+
+	handler := wallet.New(store, wallet.WithRates(rates))
+	users := user.New(store)
+	keys := apikey.New(store)
+	idempotent := idempotency.Middleware(store, idempotency.WithScope(func(c echo.Context) string {
+		principal, _ := auth.PrincipalFrom(c)
+		return principal.ID()
+	}))
+	read := auth.RequireScope(auth.ScopeWalletsRead)
+	write := auth.RequireScope(auth.ScopeWalletsWrite)
+
+	api := e.Group("/api/v1", auth.Middleware(jwt, apikey.NewAuthenticator(store)))
+	api.GET("/wallets", handler.WalletHandler, read)
+	api.POST("/wallets", handler.CreateWalletHandler, write, idempotent)
+	api.PUT("/wallets", handler.UpdateWalletHandler, write, idempotent)
+	api.GET("/wallets/:id", handler.WalletByIdHandler, read)
+	api.DELETE("/wallets/:id", handler.DeleteWalletHandler, write, idempotent)
+	api.POST("/wallets/:id/deposits", handler.DepositHandler, write, idempotent)
+	api.POST("/wallets/:id/withdrawals", handler.WithdrawHandler, write, idempotent)
+	api.GET("/wallets/:id/transactions", handler.TransactionHandler, read)
+	api.GET("/users", users.UsersHandler, auth.RequireScope(auth.ScopeUsersRead))
+	api.POST("/users", users.CreateUserHandler, auth.RequireScope(auth.ScopeUsersWrite), idempotent)
+	api.PUT("/users", users.UpdateUserHandler, auth.RequireScope(auth.ScopeUsersWrite), idempotent)
+	api.DELETE("/users/:id/wallets", handler.DeleteWalletByUserIdHandler, write, idempotent)
+	api.GET("/users/:id/wallets", handler.WalletByUserIdHandler, read)
+	api.POST("/transfers", handler.TransferHandler, auth.RequireScope(auth.ScopeTransfersCreate), idempotent)
+	api.GET("/ledger/reconciliation", handler.ReconcileHandler, auth.RequireScope(auth.ScopeLedgerRead))
+	api.POST("/api-keys", keys.CreateKeyHandler)
+	api.GET("/api-keys", keys.KeysHandler)
+	api.DELETE("/api-keys/:id", keys.RevokeKeyHandler)
+
+	go func() {
+		logger.Info("server started", "addr", cfg.Server.Addr, "version", health.Version)
+		if err := e.Start(cfg.Server.Addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("server failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+	if err := startup(ctx, cfg, store, logger); err != nil && ctx.Err() == nil {
+		logger.Error("startup failed", "error", err)
+		os.Exit(1)
+	}
+	probes.Ready()
+	<-ctx.Done()
+	// a second signal kills the process without waiting for the drain
+	stop()
+
+	logger.Info("shutting down, draining in-flight requests")
+	probes.ShuttingDown()
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		logger.Error("shutdown", "error", err)
+	}
+	if closer, ok := store.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Error("closing store", "error", err)
+		}
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("flushing spans", "error", err)
+	}
 }
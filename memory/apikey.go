@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/apikey"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+type apiKey struct {
+	apikey.Key
+	hash string
+}
+
+func (m *Memory) CreateAPIKey(ctx context.Context, k apikey.Key, hash string) (apikey.Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, stored := range m.apiKeys {
+		if stored.hash == hash {
+			return apikey.Key{}, wallet.Conflict("api key already exists")
+		}
+	}
+	k.ID = m.nextID("api_key")
+	k.Scopes = append([]string(nil), k.Scopes...)
+	k.CreatedAt = m.timestamp()
+	k.RevokedAt = nil
+	m.apiKeys[k.ID] = apiKey{Key: k, hash: hash}
+	return k, nil
+}
+
+func (m *Memory) APIKeys(ctx context.Context) ([]apikey.Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var keys []apikey.Key
+	for _, k := range m.apiKeys {
+		keys = append(keys, k.Key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+func (m *Memory) APIKeyByHash(ctx context.Context, hash string) (apikey.Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range m.apiKeys {
+		if k.hash == hash {
+			return k.Key, nil
+		}
+	}
+	return apikey.Key{}, apikey.ErrKeyNotFound
+}
+
+// RevokeAPIKey keeps the original revocation time when called again.
+func (m *Memory) RevokeAPIKey(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k, ok := m.apiKeys[id]
+	if !ok {
+		return apikey.ErrKeyNotFound
+	}
+	if k.RevokedAt == nil {
+		revokedAt := m.timestamp()
+		k.RevokedAt = &revokedAt
+		m.apiKeys[id] = k
+	}
+	return nil
+}
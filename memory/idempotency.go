@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/idempotency"
+)
+
+// ReserveIdempotencyKey claims the key, taking over records older than
+// idempotency.TTL and pending records older than idempotency.Lease.
+func (m *Memory) ReserveIdempotencyKey(ctx context.Context, record idempotency.Record) (idempotency.Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.timestamp()
+	if existing, ok := m.idempotent[record.Key]; ok {
+		ttl := idempotency.TTL
+		if !existing.Completed {
+			ttl = idempotency.Lease
+		}
+		if now.Sub(existing.CreatedAt) <= ttl {
+			return existing, false, nil
+		}
+	}
+	record.StatusCode = 0
+	record.Header = nil
+	record.Body = nil
+	record.Completed = false
+	record.CreatedAt = now
+	m.idempotent[record.Key] = record
+	return record, true, nil
+}
+
+func (m *Memory) CompleteIdempotencyKey(ctx context.Context, record idempotency.Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.idempotent[record.Key]
+	if !ok || stored.Token != record.Token {
+		return nil
+	}
+	stored.StatusCode = record.StatusCode
+	stored.Header = record.Header.Clone()
+	stored.Body = append([]byte(nil), record.Body...)
+	stored.Completed = true
+	m.idempotent[record.Key] = stored
+	return nil
+}
+
+func (m *Memory) ReleaseIdempotencyKey(ctx context.Context, record idempotency.Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.idempotent[record.Key]; ok && stored.Token == record.Token && !stored.Completed {
+		delete(m.idempotent, record.Key)
+	}
+	return nil
+}
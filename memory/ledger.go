@@ -0,0 +1,237 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+// Ledger transaction kinds and accounts, as in the postgres package.
+const (
+	ledgerOpening    = "opening"
+	ledgerTransfer   = "transfer"
+	ledgerDeposit    = "deposit"
+	ledgerWithdrawal = "withdrawal"
+	ledgerClosing    = "closing"
+
+	accountWallet   = "wallet"
+	accountExternal = "external"
+	accountFX       = "fx"
+)
+
+type ledgerTransaction struct {
+	ID          int
+	Kind        string
+	ReferenceID *int
+	CreatedAt   time.Time
+}
+
+type entry struct {
+	ID            int
+	TransactionID int
+	Account       string
+	WalletID      int
+	Currency      string
+	Amount        wallet.Money
+	CreatedAt     time.Time
+}
+
+func walletLeg(walletID int, currency string, amount wallet.Money) entry {
+	return entry{Account: accountWallet, WalletID: walletID, Currency: currency, Amount: amount}
+}
+
+func externalLeg(currency string, amount wallet.Money) entry {
+	return entry{Account: accountExternal, Currency: currency, Amount: amount}
+}
+
+func fxLeg(currency string, amount wallet.Money) entry {
+	return entry{Account: accountFX, Currency: currency, Amount: amount}
+}
+
+// postLedger records a balanced ledger transaction. Callers hold m.mu.
+func (m *Memory) postLedger(kind string, referenceID *int, entries ...entry) {
+	sums := map[string]wallet.Money{}
+	for _, e := range entries {
+		sums[e.Currency] += e.Amount
+	}
+	for currency, sum := range sums {
+		if sum != 0 {
+			panic(fmt.Sprintf("unbalanced %s ledger transaction: %s entries sum to %s", kind, currency, sum))
+		}
+	}
+
+	t := ledgerTransaction{ID: m.nextID("ledger_transaction"), Kind: kind, ReferenceID: referenceID, CreatedAt: m.timestamp()}
+	m.ledger = append(m.ledger, t)
+	for _, e := range entries {
+		e.ID = m.nextID("ledger_entry")
+		e.TransactionID = t.ID
+		e.CreatedAt = t.CreatedAt
+		m.entries = append(m.entries, e)
+	}
+}
+
+// Reconcile lists wallets whose stored balance differs from their ledger sum.
+func (m *Memory) Reconcile(ctx context.Context) ([]wallet.Reconciliation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sums := map[int]wallet.Money{}
+	for _, e := range m.entries {
+		if e.Account == accountWallet {
+			sums[e.WalletID] += e.Amount
+		}
+	}
+	reconciliations := []wallet.Reconciliation{}
+	for _, w := range m.wallets {
+		if w.Balance != sums[w.ID] {
+			reconciliations = append(reconciliations, wallet.Reconciliation{
+				WalletID:      w.ID,
+				Balance:       w.Balance,
+				LedgerBalance: sums[w.ID],
+				Difference:    w.Balance - sums[w.ID],
+			})
+		}
+	}
+	sort.Slice(reconciliations, func(i, j int) bool { return reconciliations[i].WalletID < reconciliations[j].WalletID })
+	return reconciliations, nil
+}
+
+// Transactions returns ledger entries for one wallet, newest first.
+func (m *Memory) Transactions(ctx context.Context, filter wallet.TransactionFilter) ([]wallet.Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.wallets[filter.WalletID]; !ok {
+		return nil, wallet.ErrWalletNotFound
+	}
+
+	var transactions []wallet.Transaction
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		e := m.entries[i]
+		if e.Account != accountWallet || e.WalletID != filter.WalletID {
+			continue
+		}
+		amount := e.Amount
+		if amount < 0 {
+			amount = -amount
+		}
+		switch {
+		case filter.BeforeID > 0 && e.ID >= filter.BeforeID:
+			continue
+		case !filter.From.IsZero() && e.CreatedAt.Before(filter.From):
+			continue
+		case !filter.To.IsZero() && !e.CreatedAt.Before(filter.To):
+			continue
+		case filter.MinAmount != nil && amount < *filter.MinAmount:
+			continue
+		case filter.MaxAmount != nil && amount > *filter.MaxAmount:
+			continue
+		}
+
+		t := m.ledger[e.TransactionID-1]
+		transactions = append(transactions, wallet.Transaction{
+			ID:            e.ID,
+			TransactionID: t.ID,
+			WalletID:      e.WalletID,
+			Kind:          t.Kind,
+			ReferenceID:   t.ReferenceID,
+			Amount:        e.Amount,
+			Currency:      e.Currency,
+			CreatedAt:     e.CreatedAt,
+		})
+		if filter.Limit > 0 && len(transactions) == filter.Limit {
+			break
+		}
+	}
+	return transactions, nil
+}
+
+func (m *Memory) Deposit(ctx context.Context, walletID int, amount wallet.Money) (wallet.Wallet, error) {
+	return m.move(walletID, amount, ledgerDeposit)
+}
+
+func (m *Memory) Withdraw(ctx context.Context, walletID int, amount wallet.Money) (wallet.Wallet, error) {
+	return m.move(walletID, -amount, ledgerWithdrawal)
+}
+
+// move applies delta to the wallet balance, rejecting overdrafts for
+// wallet types that do not allow them and balances beyond maxAmount.
+func (m *Memory) move(walletID int, delta wallet.Money, kind string) (wallet.Wallet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, ok := m.wallets[walletID]
+	if !ok {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	if !wallet.FitsCurrency(delta, w.Currency) {
+		return wallet.Wallet{}, wallet.ErrAmountPrecision
+	}
+	if w.Balance+delta < 0 && !wallet.CanOverdraft(w.WalletType) {
+		return wallet.Wallet{}, wallet.ErrInsufficientFunds
+	}
+	if !inRange(w.Balance + delta) {
+		return wallet.Wallet{}, errOutOfRange("balance")
+	}
+
+	w.Balance += delta
+	w.Version++
+	m.wallets[walletID] = w
+	m.postLedger(kind, nil, walletLeg(walletID, w.Currency, delta), externalLeg(w.Currency, -delta))
+	return m.withUser(w), nil
+}
+
+// Transfer moves money between two wallets atomically. The caller quotes
+// the transfer: Amount is debited in Currency and CreditAmount credited
+// in CreditCurrency.
+func (m *Memory) Transfer(ctx context.Context, t wallet.Transfer) (wallet.Transfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from, ok := m.wallets[t.FromWalletID]
+	if !ok {
+		return wallet.Transfer{}, wallet.ErrWalletNotFound
+	}
+	to, ok := m.wallets[t.ToWalletID]
+	if !ok {
+		return wallet.Transfer{}, wallet.ErrWalletNotFound
+	}
+	if from.Currency != t.Currency || to.Currency != t.CreditCurrency {
+		return wallet.Transfer{}, fmt.Errorf("transfer quoted %s to %s but wallets hold %s and %s", t.Currency, t.CreditCurrency, from.Currency, to.Currency)
+	}
+	if from.Balance < t.Amount && !wallet.CanOverdraft(from.WalletType) {
+		return wallet.Transfer{}, wallet.ErrInsufficientFunds
+	}
+	if !inRange(t.Amount) || !inRange(t.CreditAmount) {
+		return wallet.Transfer{}, errOutOfRange("amount")
+	}
+	if !inRange(from.Balance-t.Amount) || !inRange(to.Balance+t.CreditAmount) {
+		return wallet.Transfer{}, errOutOfRange("balance")
+	}
+
+	from.Balance -= t.Amount
+	from.Version++
+	m.wallets[from.ID] = from
+	to = m.wallets[t.ToWalletID]
+	to.Balance += t.CreditAmount
+	to.Version++
+	m.wallets[to.ID] = to
+
+	t.ID = m.nextID("wallet_transfer")
+	t.CreatedAt = m.timestamp()
+	m.transfers = append(m.transfers, t)
+
+	entries := []entry{
+		walletLeg(t.FromWalletID, t.Currency, -t.Amount),
+		walletLeg(t.ToWalletID, t.CreditCurrency, t.CreditAmount),
+	}
+	if t.Currency != t.CreditCurrency {
+		entries = append(entries, fxLeg(t.Currency, t.Amount), fxLeg(t.CreditCurrency, -t.CreditAmount))
+	}
+	id := t.ID
+	m.postLedger(ledgerTransfer, &id, entries...)
+	return t, nil
+}
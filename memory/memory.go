@@ -0,0 +1,89 @@
+// Package memory is an in-process implementation of the wallet, user, API
+// key and idempotency stores for local development and tests. It mirrors
+// the postgres package: ids start at 1 per table, wallets carry their
+// owner's name, every balance change is booked to a ledger and missing
+// rows return the same not-found errors.
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/apikey"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/idempotency"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/user"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+// Memory is safe for concurrent use. A single lock covers every table so
+// multi-wallet operations are atomic, like a database transaction.
+type Memory struct {
+	mu sync.Mutex
+
+	users      map[int]user.User
+	wallets    map[int]wallet.Wallet
+	transfers  []wallet.Transfer
+	ledger     []ledgerTransaction
+	entries    []entry
+	apiKeys    map[int]apiKey
+	idempotent map[string]idempotency.Record
+	ids        map[string]int
+
+	// now is replaced in tests that need control over timestamps.
+	now func() time.Time
+}
+
+func New() *Memory {
+	return &Memory{
+		users:      map[int]user.User{},
+		wallets:    map[int]wallet.Wallet{},
+		apiKeys:    map[int]apiKey{},
+		idempotent: map[string]idempotency.Record{},
+		ids:        map[string]int{},
+		now:        time.Now,
+	}
+}
+
+// nextID hands out sequential ids per table, like a SERIAL column.
+func (m *Memory) nextID(table string) int {
+	m.ids[table]++
+	return m.ids[table]
+}
+
+// timestamp matches the microsecond precision of a Postgres TIMESTAMP.
+func (m *Memory) timestamp() time.Time {
+	return m.now().UTC().Truncate(time.Microsecond)
+}
+
+// Seed loads the same sample users and wallets as postgres/seed.sql into
+// an empty store.
+func (m *Memory) Seed() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.users) > 0 {
+		return
+	}
+
+	for _, name := range []string{"John Doe", "Jane Doe"} {
+		id := m.nextID("users")
+		m.users[id] = user.User{ID: id, Name: name, CreatedAt: m.timestamp()}
+	}
+	for _, w := range []wallet.Wallet{
+		{UserID: 1, WalletName: "John Savings", WalletType: wallet.Savings, Balance: wallet.MustParseMoney("1000.00")},
+		{UserID: 1, WalletName: "John Credit Card", WalletType: wallet.CreditCard, Balance: wallet.MustParseMoney("500.00")},
+		{UserID: 1, WalletName: "John Crypto Wallet", WalletType: wallet.CryptoWallet, Balance: wallet.MustParseMoney("100.00")},
+		{UserID: 2, WalletName: "Jane Savings", WalletType: wallet.Savings, Balance: wallet.MustParseMoney("2000.00")},
+		{UserID: 2, WalletName: "Jane Credit Card", WalletType: wallet.CreditCard, Balance: wallet.MustParseMoney("1000.00")},
+		{UserID: 2, WalletName: "Jane Crypto Wallet", WalletType: wallet.CryptoWallet, Balance: wallet.MustParseMoney("200.00")},
+	} {
+		w.Currency = wallet.DefaultCurrency
+		m.insertWallet(w)
+	}
+}
+
+var (
+	_ wallet.Storer      = (*Memory)(nil)
+	_ user.Storer        = (*Memory)(nil)
+	_ apikey.Storer      = (*Memory)(nil)
+	_ idempotency.Storer = (*Memory)(nil)
+)
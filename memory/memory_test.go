@@ -0,0 +1,211 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/auth"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/idempotency"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/user"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+	"github.com/labstack/echo/v4"
+)
+
+func TestWallets(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("given seeded store should number wallets from 1 and filter by type", func(t *testing.T) {
+		m := New()
+		m.Seed()
+
+		savings, err := m.Wallets(ctx, wallet.WalletFilter{WalletType: wallet.Savings})
+
+		if err != nil || len(savings) != 2 {
+			t.Fatalf("expected 2 Savings wallets, got %v, %v", savings, err)
+		}
+		if savings[0].ID != 1 || savings[0].UserName != "John Doe" || savings[1].ID != 4 {
+			t.Errorf("expected wallets 1 and 4 with owner names, got %+v", savings)
+		}
+	})
+
+	t.Run("given missing rows should return not found errors", func(t *testing.T) {
+		m := New()
+
+		if _, err := m.WalletById(ctx, 1); !errors.Is(err, wallet.ErrWalletNotFound) {
+			t.Errorf("expected ErrWalletNotFound, got %v", err)
+		}
+		if err := m.DeleteWallet(ctx, 1); !errors.Is(err, wallet.ErrNotFound) {
+			t.Errorf("expected not found deleting wallet, got %v", err)
+		}
+		if err := m.DeleteWalletByUserId(ctx, "1"); !errors.Is(err, wallet.ErrNotFound) {
+			t.Errorf("expected not found deleting user wallets, got %v", err)
+		}
+		if _, err := m.UserById(ctx, 1); !errors.Is(err, user.ErrUserNotFound) {
+			t.Errorf("expected ErrUserNotFound, got %v", err)
+		}
+		if _, err := m.CreateWallet(ctx, wallet.Wallet{UserID: 1}); !errors.Is(err, wallet.ErrValidation) {
+			t.Errorf("expected validation error for unknown user, got %v", err)
+		}
+	})
+
+	t.Run("given wallet moves should keep the ledger reconciled", func(t *testing.T) {
+		m := New()
+		m.Seed()
+
+		m.Deposit(ctx, 1, wallet.MustParseMoney("10"))
+		m.Withdraw(ctx, 2, wallet.MustParseMoney("600"))
+		m.Transfer(ctx, wallet.Transfer{FromWalletID: 4, ToWalletID: 1, Amount: wallet.MustParseMoney("5"), Currency: "THB", CreditAmount: wallet.MustParseMoney("5"), CreditCurrency: "THB"})
+		m.DeleteWallet(ctx, 3)
+		reconciliations, err := m.Reconcile(ctx)
+
+		if err != nil || len(reconciliations) != 0 {
+			t.Errorf("expected no differences, got %v, %v", reconciliations, err)
+		}
+		if _, err := m.Withdraw(ctx, 1, wallet.MustParseMoney("5000")); !errors.Is(err, wallet.ErrInsufficientFunds) {
+			t.Errorf("expected insufficient funds for savings overdraft, got %v", err)
+		}
+		history, _ := m.Transactions(ctx, wallet.TransactionFilter{WalletID: 1, Limit: 10})
+		if len(history) != 3 || history[0].Kind != ledgerTransfer || history[2].Kind != ledgerOpening {
+			t.Errorf("expected transfer, deposit and opening newest first, got %+v", history)
+		}
+	})
+
+	t.Run("given concurrent deposits should apply every one", func(t *testing.T) {
+		m := New()
+		m.Seed()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				m.Deposit(ctx, 1, wallet.MustParseMoney("1"))
+			}()
+		}
+		wg.Wait()
+
+		w, _ := m.WalletById(ctx, 1)
+		if w.Balance != wallet.MustParseMoney("1050") || w.Version != 51 {
+			t.Errorf("expected balance 1050 at version 51, got %s at %d", w.Balance, w.Version)
+		}
+	})
+}
+
+func TestHandlerFlow(t *testing.T) {
+	m := New()
+	m.Seed()
+	h := wallet.New(m)
+	e := echo.New()
+	e.Validator = wallet.NewValidator()
+	send := func(method, path, body string, handler echo.HandlerFunc, params ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		if len(params) == 2 {
+			c.SetParamNames(params[0])
+			c.SetParamValues(params[1])
+		}
+		auth.SetPrincipal(c, auth.Principal{UserID: 2})
+		if err := handler(c); err != nil {
+			wallet.ErrorHandler(err, c)
+		}
+		return rec
+	}
+
+	rec := send(http.MethodPost, "/api/v1/wallets", `{"user_id": 2, "wallet_name": "Jane Travel", "wallet_type": "Savings", "balance": 50}`, h.CreateWalletHandler)
+	var created wallet.Wallet
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if rec.Code != http.StatusCreated || created.ID != 7 {
+		t.Fatalf("expected wallet 7 created, got %d and %s", rec.Code, rec.Body.String())
+	}
+
+	rec = send(http.MethodGet, "/api/v1/wallets?wallet_type=Savings", "", h.WalletHandler)
+	var page wallet.WalletPage
+	json.Unmarshal(rec.Body.Bytes(), &page)
+	if rec.Code != http.StatusOK || len(page.Wallets) != 2 || page.Wallets[1].ID != created.ID || page.Wallets[1].UserName != "Jane Doe" {
+		t.Errorf("expected Jane's two Savings wallets, got %d and %s", rec.Code, rec.Body.String())
+	}
+
+	rec = send(http.MethodGet, "/api/v1/wallets/1", "", h.WalletByIdHandler, "id", "1")
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 reading John's wallet, got %d", rec.Code)
+	}
+}
+
+func TestWalletTotals(t *testing.T) {
+	m := New()
+	m.Seed()
+
+	totals, err := m.WalletTotals(context.Background())
+
+	want := []wallet.WalletTotal{
+		{WalletType: wallet.CreditCard, Currency: "THB", Wallets: 2, Balance: wallet.MustParseMoney("1500")},
+		{WalletType: wallet.CryptoWallet, Currency: "THB", Wallets: 2, Balance: wallet.MustParseMoney("300")},
+		{WalletType: wallet.Savings, Currency: "THB", Wallets: 2, Balance: wallet.MustParseMoney("3000")},
+	}
+	if err != nil || !reflect.DeepEqual(totals, want) {
+		t.Errorf("expected %+v, got %+v, %v", want, totals, err)
+	}
+}
+
+func TestReserveIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := New()
+	m.now = func() time.Time { return now }
+	record := idempotency.Record{Key: "1:abc", Token: "t1", Method: http.MethodPost, Path: "/api/v1/wallets", RequestHash: "h"}
+
+	t.Run("given pending key within its lease should report it in progress", func(t *testing.T) {
+		m.ReserveIdempotencyKey(ctx, record)
+		now = now.Add(idempotency.Lease)
+
+		existing, reserved, err := m.ReserveIdempotencyKey(ctx, record)
+
+		if err != nil || reserved || existing.Completed {
+			t.Errorf("expected pending record, got %+v, %v, %v", existing, reserved, err)
+		}
+	})
+
+	t.Run("given pending key past its lease should take it over", func(t *testing.T) {
+		now = now.Add(time.Second)
+
+		if _, reserved, err := m.ReserveIdempotencyKey(ctx, record); err != nil || !reserved {
+			t.Errorf("expected abandoned key to be reserved again, got %v, %v", reserved, err)
+		}
+	})
+
+	t.Run("given an expired reservation should not complete or release the takeover", func(t *testing.T) {
+		stale := record
+		stale.Token = "stale"
+		stale.StatusCode = http.StatusCreated
+		m.CompleteIdempotencyKey(ctx, stale)
+		m.ReleaseIdempotencyKey(ctx, stale)
+
+		existing, reserved, err := m.ReserveIdempotencyKey(ctx, record)
+
+		if err != nil || reserved || existing.Completed {
+			t.Errorf("expected the takeover to stay pending, got %+v, %v, %v", existing, reserved, err)
+		}
+	})
+
+	t.Run("given completed key past the lease should keep replaying it", func(t *testing.T) {
+		record.StatusCode = http.StatusCreated
+		m.CompleteIdempotencyKey(ctx, record)
+		now = now.Add(2 * idempotency.Lease)
+
+		existing, reserved, err := m.ReserveIdempotencyKey(ctx, record)
+
+		if err != nil || reserved || existing.StatusCode != http.StatusCreated {
+			t.Errorf("expected stored response, got %+v, %v, %v", existing, reserved, err)
+		}
+	})
+}
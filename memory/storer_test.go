@@ -0,0 +1,19 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/user"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet/wallettest"
+)
+
+func TestStorer(t *testing.T) {
+	wallettest.Run(t, func(t *testing.T) (wallet.Storer, [2]int) {
+		m := New()
+		john, _ := m.CreateUser(context.Background(), user.User{Name: "John Doe"})
+		jane, _ := m.CreateUser(context.Background(), user.User{Name: "Jane Doe"})
+		return m, [2]int{john.ID, jane.ID}
+	})
+}
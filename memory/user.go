@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/user"
+)
+
+func (m *Memory) Users(ctx context.Context) ([]user.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var users []user.User
+	for _, u := range m.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (m *Memory) UserById(ctx context.Context, id int) (user.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[id]
+	if !ok {
+		return user.User{}, user.ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (m *Memory) CreateUser(ctx context.Context, u user.User) (user.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u.ID = m.nextID("users")
+	u.CreatedAt = m.timestamp()
+	m.users[u.ID] = u
+	return u, nil
+}
+
+func (m *Memory) UpdateUser(ctx context.Context, u user.User) (user.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.users[u.ID]
+	if !ok {
+		return user.User{}, user.ErrUserNotFound
+	}
+	stored.Name = u.Name
+	m.users[u.ID] = stored
+	return stored, nil
+}
@@ -0,0 +1,259 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+var errUserNotFound = wallet.NewValidationError("user_id", "user does not exist")
+
+// maxAmount is the largest value of the DECIMAL(10, 2) balance and amount
+// columns in postgres, which rejects anything beyond it as a validation
+// error.
+const maxAmount = wallet.Money(9999999999)
+
+func inRange(m wallet.Money) bool {
+	return -maxAmount <= m && m <= maxAmount
+}
+
+func errOutOfRange(field string) error {
+	return wallet.NewValidationError(field, "numeric field overflow")
+}
+
+// withUser fills in the owner's name, which postgres reads from users.
+func (m *Memory) withUser(w wallet.Wallet) wallet.Wallet {
+	w.UserName = m.users[w.UserID].Name
+	return w
+}
+
+func (m *Memory) Wallets(ctx context.Context, filter wallet.WalletFilter) ([]wallet.Wallet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var wallets []wallet.Wallet
+	for _, w := range m.wallets {
+		if matches(w, filter) {
+			wallets = append(wallets, m.withUser(w))
+		}
+	}
+	sort.Slice(wallets, func(i, j int) bool {
+		return compareWallets(wallets[i], wallets[j], filter.Sort, filter.Desc) < 0
+	})
+	if filter.Limit > 0 && len(wallets) > filter.Limit {
+		wallets = wallets[:filter.Limit]
+	}
+	return wallets, nil
+}
+
+func matches(w wallet.Wallet, filter wallet.WalletFilter) bool {
+	switch {
+	case filter.WalletType != "" && w.WalletType != filter.WalletType:
+		return false
+	case filter.UserID > 0 && w.UserID != filter.UserID:
+		return false
+	case filter.MinBalance != nil && w.Balance < *filter.MinBalance:
+		return false
+	case filter.MaxBalance != nil && w.Balance > *filter.MaxBalance:
+		return false
+	case !filter.From.IsZero() && w.CreatedAt.Before(filter.From):
+		return false
+	case !filter.To.IsZero() && !w.CreatedAt.Before(filter.To):
+		return false
+	}
+	if after := filter.After; after != nil {
+		cursor := wallet.Wallet{ID: after.ID, Balance: after.Balance, CreatedAt: after.CreatedAt}
+		return compareWallets(w, cursor, filter.Sort, filter.Desc) > 0
+	}
+	return true
+}
+
+// compareWallets orders by the sort key and then id, both reversed when
+// desc is set, matching the keyset used by postgres.
+func compareWallets(a, b wallet.Wallet, key string, desc bool) int {
+	c := 0
+	switch key {
+	case wallet.SortBalance:
+		c = compareInt(int64(a.Balance), int64(b.Balance))
+	case wallet.SortCreatedAt:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if c == 0 {
+		c = compareInt(int64(a.ID), int64(b.ID))
+	}
+	if desc {
+		return -c
+	}
+	return c
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (m *Memory) CreateWallet(ctx context.Context, w wallet.Wallet) (wallet.Wallet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[w.UserID]; !ok {
+		return wallet.Wallet{}, errUserNotFound
+	}
+	if !inRange(w.Balance) {
+		return wallet.Wallet{}, errOutOfRange("balance")
+	}
+	return m.withUser(m.insertWallet(w)), nil
+}
+
+func (m *Memory) insertWallet(w wallet.Wallet) wallet.Wallet {
+	w.ID = m.nextID("user_wallet")
+	w.UserName = ""
+	w.CreatedAt = m.timestamp()
+	w.Version = 1
+	if w.Currency == "" {
+		w.Currency = wallet.DefaultCurrency
+	}
+	m.wallets[w.ID] = w
+	if w.Balance != 0 {
+		m.postLedger(ledgerOpening, nil, walletLeg(w.ID, w.Currency, w.Balance), externalLeg(w.Currency, -w.Balance))
+	}
+	return w
+}
+
+// UpdateWallet follows postgres.UpdateWallet: Version is required and
+// must match, and w.Balance is ignored.
+func (m *Memory) UpdateWallet(ctx context.Context, w wallet.Wallet) (wallet.Wallet, error) {
+	if w.Version == 0 {
+		return wallet.Wallet{}, wallet.ErrVersionRequired
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.wallets[w.ID]
+	if !ok {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	if w.Version != stored.Version {
+		return wallet.Wallet{}, wallet.ErrVersionConflict
+	}
+	if w.Currency != "" && w.Currency != stored.Currency {
+		return wallet.Wallet{}, wallet.ErrCurrencyChange
+	}
+	if _, ok := m.users[w.UserID]; !ok {
+		return wallet.Wallet{}, errUserNotFound
+	}
+
+	stored.UserID = w.UserID
+	stored.WalletName = w.WalletName
+	stored.WalletType = w.WalletType
+	stored.Version++
+	m.wallets[stored.ID] = stored
+	return m.withUser(stored), nil
+}
+
+func (m *Memory) DeleteWalletByUserId(ctx context.Context, userId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, err := strconv.Atoi(userId)
+	if err != nil {
+		return wallet.NewValidationError("user_id", "must be a user id")
+	}
+	if m.closeWallets(func(w wallet.Wallet) bool { return w.UserID == id }) == 0 {
+		return wallet.NotFound("no wallets found for user id " + userId)
+	}
+	return nil
+}
+
+func (m *Memory) DeleteWallet(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closeWallets(func(w wallet.Wallet) bool { return w.ID == id }) == 0 {
+		return wallet.ErrWalletNotFound
+	}
+	return nil
+}
+
+// closeWallets books the remaining balances out to the external account
+// before deleting the wallets, as postgres does.
+func (m *Memory) closeWallets(match func(wallet.Wallet) bool) int {
+	var ids []int
+	for _, w := range m.wallets {
+		if match(w) {
+			ids = append(ids, w.ID)
+		}
+	}
+	sort.Ints(ids)
+
+	var closing []entry
+	for _, id := range ids {
+		if w := m.wallets[id]; w.Balance != 0 {
+			closing = append(closing, walletLeg(w.ID, w.Currency, -w.Balance), externalLeg(w.Currency, w.Balance))
+		}
+	}
+	if len(closing) > 0 {
+		m.postLedger(ledgerClosing, nil, closing...)
+	}
+	for _, id := range ids {
+		delete(m.wallets, id)
+	}
+	return len(ids)
+}
+
+func (m *Memory) WalletByUserId(ctx context.Context, userId string) ([]wallet.Wallet, error) {
+	id, err := strconv.Atoi(userId)
+	if err != nil {
+		return nil, wallet.NewValidationError("user_id", "must be a user id")
+	}
+	if id <= 0 {
+		return nil, nil
+	}
+	return m.Wallets(ctx, wallet.WalletFilter{UserID: id})
+}
+
+func (m *Memory) WalletById(ctx context.Context, id int) (wallet.Wallet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, ok := m.wallets[id]
+	if !ok {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	return m.withUser(w), nil
+}
+
+func (m *Memory) WalletTotals(ctx context.Context) ([]wallet.WalletTotal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	byKey := map[[2]string]*wallet.WalletTotal{}
+	for _, w := range m.wallets {
+		key := [2]string{w.WalletType, w.Currency}
+		t, ok := byKey[key]
+		if !ok {
+			t = &wallet.WalletTotal{WalletType: w.WalletType, Currency: w.Currency}
+			byKey[key] = t
+		}
+		t.Wallets++
+		t.Balance += w.Balance
+	}
+	totals := make([]wallet.WalletTotal, 0, len(byKey))
+	for _, t := range byKey {
+		totals = append(totals, *t)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].WalletType != totals[j].WalletType {
+			return totals[i].WalletType < totals[j].WalletType
+		}
+		return totals[i].Currency < totals[j].Currency
+	})
+	return totals, nil
+}
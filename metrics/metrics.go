@@ -0,0 +1,131 @@
+// Package metrics exposes Prometheus metrics for HTTP requests, the
+// database pool and wallet balances.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// totalsTimeout keeps a slow database from hanging a scrape.
+const totalsTimeout = 5 * time.Second
+
+// unmatchedRoute labels requests that matched no route, so random paths
+// do not create new series.
+const unmatchedRoute = "unmatched"
+
+// Totals is implemented by the stores.
+type Totals interface {
+	WalletTotals(ctx context.Context) ([]wallet.WalletTotal, error)
+}
+
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// New registers the HTTP metrics together with the Go runtime and process
+// collectors on a registry of its own.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by method and route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+	m.registry.MustRegister(
+		m.requests,
+		m.duration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// RegisterDB exports the database/sql pool stats as go_sql_* metrics.
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterTotals exports wallet counts and balances per wallet type and
+// currency, read from totals on every scrape.
+func (m *Metrics) RegisterTotals(totals Totals) {
+	m.registry.MustRegister(newTotalsCollector(totals))
+}
+
+// Middleware counts and times every request by its route template.
+func (m *Metrics) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		if err := next(c); err != nil {
+			c.Error(err)
+		}
+
+		route := c.Path()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request().Method
+		m.requests.WithLabelValues(method, route, strconv.Itoa(c.Response().Status)).Inc()
+		m.duration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		return nil
+	}
+}
+
+// Handler serves the metrics in the Prometheus text format. A failing
+// collector is reported in the response without hiding the others.
+func (m *Metrics) Handler() echo.HandlerFunc {
+	return echo.WrapHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	}))
+}
+
+type totalsCollector struct {
+	totals  Totals
+	wallets *prometheus.Desc
+	balance *prometheus.Desc
+}
+
+func newTotalsCollector(totals Totals) *totalsCollector {
+	labels := []string{"wallet_type", "currency"}
+	return &totalsCollector{
+		totals:  totals,
+		wallets: prometheus.NewDesc("wallets", "Wallets by type and currency.", labels, nil),
+		balance: prometheus.NewDesc("wallet_balance", "Sum of wallet balances by type and currency, in major units of the currency.", labels, nil),
+	}
+}
+
+func (c *totalsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.wallets
+	ch <- c.balance
+}
+
+func (c *totalsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), totalsTimeout)
+	defer cancel()
+
+	totals, err := c.totals.WalletTotals(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.balance, err)
+		return
+	}
+	for _, t := range totals {
+		ch <- prometheus.MustNewConstMetric(c.wallets, prometheus.GaugeValue, float64(t.Wallets), t.WalletType, t.Currency)
+		ch <- prometheus.MustNewConstMetric(c.balance, prometheus.GaugeValue, t.Balance.Float64(), t.WalletType, t.Currency)
+	}
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type StubTotals struct {
+	totals []wallet.WalletTotal
+	err    error
+}
+
+func (s StubTotals) WalletTotals(ctx context.Context) ([]wallet.WalletTotal, error) {
+	return s.totals, s.err
+}
+
+func setup(totals Totals) (*echo.Echo, *Metrics) {
+	m := New()
+	m.RegisterTotals(totals)
+	e := echo.New()
+	e.Use(m.Middleware)
+	e.GET("/metrics", m.Handler())
+	e.GET("/api/v1/wallets/:id", func(c echo.Context) error {
+		if c.Param("id") == "0" {
+			return echo.NewHTTPError(http.StatusBadRequest)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+	return e, m
+}
+
+func get(e *echo.Echo, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	e, m := setup(StubTotals{})
+
+	get(e, "/api/v1/wallets/1")
+	get(e, "/api/v1/wallets/2")
+	get(e, "/api/v1/wallets/0")
+	get(e, "/random/path")
+
+	cases := []struct {
+		labels []string
+		want   float64
+	}{
+		{[]string{"GET", "/api/v1/wallets/:id", "200"}, 2},
+		{[]string{"GET", "/api/v1/wallets/:id", "400"}, 1},
+		{[]string{"GET", unmatchedRoute, "404"}, 1},
+	}
+	for _, tc := range cases {
+		if got := testutil.ToFloat64(m.requests.WithLabelValues(tc.labels...)); got != tc.want {
+			t.Errorf("expected %v requests for %v, got %v", tc.want, tc.labels, got)
+		}
+	}
+	if got := testutil.CollectAndCount(m.duration); got != 2 {
+		t.Errorf("expected latency series for the route and unmatched, got %d", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	t.Run("given wallet totals should export them per type and currency", func(t *testing.T) {
+		e, _ := setup(StubTotals{totals: []wallet.WalletTotal{
+			{WalletType: wallet.Savings, Currency: "THB", Wallets: 2, Balance: wallet.MustParseMoney("1500.25")},
+			{WalletType: wallet.CreditCard, Currency: "USD", Wallets: 1, Balance: wallet.MustParseMoney("-20")},
+		}})
+
+		rec := get(e, "/metrics")
+
+		for _, want := range []string{
+			`wallets{currency="THB",wallet_type="Savings"} 2`,
+			`wallet_balance{currency="THB",wallet_type="Savings"} 1500.25`,
+			`wallet_balance{currency="USD",wallet_type="Credit Card"} -20`,
+			`go_goroutines`,
+		} {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Errorf("expected %s in metrics", want)
+			}
+		}
+	})
+
+	t.Run("given totals fail should still serve the other metrics", func(t *testing.T) {
+		e, _ := setup(StubTotals{err: errors.New("connection refused")})
+
+		rec := get(e, "/metrics")
+
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "go_goroutines") || strings.Contains(rec.Body.String(), "wallet_balance{") {
+			t.Errorf("expected 200 without wallet totals, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+}
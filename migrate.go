@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/postgres"
+)
+
+const migrateUsage = "usage: app migrate [up | down [steps] | version]"
+
+// migrate runs the migrate subcommand: up applies pending migrations,
+// down reverts the latest one (or steps of them) and version prints the
+// applied schema version.
+func migrate(ctx context.Context, store Store, args []string) error {
+	p, ok := store.(*postgres.Postgres)
+	if !ok {
+		return errors.New("migrate needs the postgres driver")
+	}
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch {
+	case command == "up" && len(args) <= 1:
+		return p.Migrate(ctx)
+	case command == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("steps must be a positive number\n%s", migrateUsage)
+			}
+			steps = n
+		}
+		return p.MigrateDown(ctx, steps)
+	case command == "version" && len(args) <= 1:
+		version, err := p.MigrationVersion(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Println(version)
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+}
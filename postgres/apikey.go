@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/apikey"
+	"github.com/lib/pq"
+)
+
+const apiKeyColumns = "id, name, prefix, scopes, created_at, revoked_at"
+
+func scanAPIKey(row scanner) (apikey.Key, error) {
+	var k apikey.Key
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, pq.Array(&k.Scopes), &k.CreatedAt, &k.RevokedAt)
+	return k, err
+}
+
+func (p *Postgres) CreateAPIKey(ctx context.Context, k apikey.Key, hash string) (apikey.Key, error) {
+	created, err := scanAPIKey(p.Db.QueryRowContext(ctx, "INSERT INTO api_key (name, prefix, key_hash, scopes) VALUES ($1, $2, $3, $4) RETURNING "+apiKeyColumns,
+		k.Name, k.Prefix, hash, pq.Array(k.Scopes),
+	))
+	if err != nil {
+		return apikey.Key{}, mapError(err)
+	}
+	return created, nil
+}
+
+func (p *Postgres) APIKeys(ctx context.Context) ([]apikey.Key, error) {
+	rows, err := p.Db.QueryContext(ctx, "SELECT "+apiKeyColumns+" FROM api_key ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []apikey.Key
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (p *Postgres) APIKeyByHash(ctx context.Context, hash string) (apikey.Key, error) {
+	k, err := scanAPIKey(p.Db.QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM api_key WHERE key_hash = $1", hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return apikey.Key{}, apikey.ErrKeyNotFound
+	}
+	return k, err
+}
+
+// RevokeAPIKey keeps the original revocation time when called again.
+func (p *Postgres) RevokeAPIKey(ctx context.Context, id int) error {
+	result, err := p.Db.ExecContext(ctx, "UPDATE api_key SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return apikey.ErrKeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+	"github.com/lib/pq"
+)
+
+// mapError turns constraint and input errors reported by Postgres into
+// wallet error kinds. Other errors are returned unchanged.
+func mapError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	if pqErr.Constraint == "user_wallet_user_id_fkey" {
+		return wallet.NewValidationError("user_id", "user does not exist")
+	}
+	switch pqErr.Code {
+	case "23505": // unique_violation
+		return wallet.Conflict(pqErr.Message)
+	case "22P02", // invalid_text_representation, e.g. an unknown wallet_type
+		"22001", // string_data_right_truncation
+		"22003", // numeric_value_out_of_range
+		"23502", // not_null_violation
+		"23503", // foreign_key_violation
+		"23514": // check_violation
+		return wallet.NewValidationError(pqErr.Column, pqErr.Message)
+	default:
+		return err
+	}
+}
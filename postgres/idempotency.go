@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/idempotency"
+)
+
+// ReserveIdempotencyKey claims the key, taking over records older than
+// idempotency.TTL and pending records older than idempotency.Lease.
+func (p *Postgres) ReserveIdempotencyKey(ctx context.Context, record idempotency.Record) (idempotency.Record, bool, error) {
+	var key string
+	err := p.Db.QueryRowContext(ctx, `INSERT INTO idempotency_key (key, token, method, path, request_hash) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (key) DO UPDATE SET token = EXCLUDED.token, method = EXCLUDED.method, path = EXCLUDED.path, request_hash = EXCLUDED.request_hash,
+			status_code = NULL, response_header = NULL, response_body = NULL, created_at = CURRENT_TIMESTAMP
+		WHERE idempotency_key.created_at < CURRENT_TIMESTAMP - make_interval(secs => $6)
+			OR (idempotency_key.status_code IS NULL AND idempotency_key.created_at < CURRENT_TIMESTAMP - make_interval(secs => $7))
+		RETURNING key`,
+		record.Key, record.Token, record.Method, record.Path, record.RequestHash, idempotency.TTL.Seconds(), idempotency.Lease.Seconds(),
+	).Scan(&key)
+	if err == nil {
+		return record, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return idempotency.Record{}, false, err
+	}
+
+	existing := idempotency.Record{Key: record.Key}
+	var statusCode sql.NullInt64
+	var header []byte
+	err = p.Db.QueryRowContext(ctx, "SELECT method, path, request_hash, status_code, response_header, response_body, created_at FROM idempotency_key WHERE key = $1", record.Key).Scan(
+		&existing.Method, &existing.Path, &existing.RequestHash,
+		&statusCode, &header, &existing.Body, &existing.CreatedAt,
+	)
+	if err != nil {
+		return idempotency.Record{}, false, err
+	}
+	if statusCode.Valid {
+		existing.StatusCode = int(statusCode.Int64)
+		existing.Completed = true
+		existing.Header = http.Header{}
+		if len(header) > 0 {
+			if err := json.Unmarshal(header, &existing.Header); err != nil {
+				return idempotency.Record{}, false, err
+			}
+		}
+	}
+	return existing, false, nil
+}
+
+// CompleteIdempotencyKey records the response unless the reservation has
+// been taken over since.
+func (p *Postgres) CompleteIdempotencyKey(ctx context.Context, record idempotency.Record) error {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return err
+	}
+	_, err = p.Db.ExecContext(ctx, "UPDATE idempotency_key SET status_code = $1, response_header = $2, response_body = $3 WHERE key = $4 AND token = $5",
+		record.StatusCode, header, record.Body, record.Key, record.Token,
+	)
+	return err
+}
+
+func (p *Postgres) ReleaseIdempotencyKey(ctx context.Context, record idempotency.Record) error {
+	_, err := p.Db.ExecContext(ctx, "DELETE FROM idempotency_key WHERE key = $1 AND token = $2 AND status_code IS NULL", record.Key, record.Token)
+	return err
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+const (
+	ledgerOpening    = "opening"
+	ledgerTransfer   = "transfer"
+	ledgerDeposit    = "deposit"
+	ledgerWithdrawal = "withdrawal"
+	ledgerClosing    = "closing"
+)
+
+// Ledger accounts. Only wallet entries carry a wallet id; external is money
+// entering or leaving the system and fx is the clearing account that
+// balances each currency of a cross-currency transfer.
+const (
+	accountWallet   = "wallet"
+	accountExternal = "external"
+	accountFX       = "fx"
+)
+
+// entry is one leg of a ledger transaction.
+type entry struct {
+	Account  string
+	WalletID *int
+	Currency string
+	Amount   wallet.Money
+}
+
+func walletLeg(walletID int, currency string, amount wallet.Money) entry {
+	return entry{Account: accountWallet, WalletID: &walletID, Currency: currency, Amount: amount}
+}
+
+func externalLeg(currency string, amount wallet.Money) entry {
+	return entry{Account: accountExternal, Currency: currency, Amount: amount}
+}
+
+func fxLeg(currency string, amount wallet.Money) entry {
+	return entry{Account: accountFX, Currency: currency, Amount: amount}
+}
+
+// postLedger records a ledger transaction inside tx. The entries must
+// balance to zero in every currency.
+func postLedger(ctx context.Context, tx *sql.Tx, kind string, referenceID *int, entries ...entry) error {
+	sums := map[string]wallet.Money{}
+	for _, e := range entries {
+		sums[e.Currency] += e.Amount
+	}
+	for currency, sum := range sums {
+		if sum != 0 {
+			return fmt.Errorf("unbalanced %s ledger transaction: %s entries sum to %s", kind, currency, sum)
+		}
+	}
+
+	var transactionID int
+	err := tx.QueryRowContext(ctx, "INSERT INTO ledger_transaction (kind, reference_id) VALUES ($1, $2) RETURNING id", kind, referenceID).Scan(&transactionID)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		_, err := tx.ExecContext(ctx, "INSERT INTO ledger_entry (transaction_id, account, wallet_id, currency, amount) VALUES ($1, $2, $3, $4, $5)",
+			transactionID, e.Account, e.WalletID, e.Currency, e.Amount,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reconcile lists wallets whose stored balance differs from their ledger sum.
+func (p *Postgres) Reconcile(ctx context.Context) ([]wallet.Reconciliation, error) {
+	rows, err := p.Db.QueryContext(ctx, `SELECT w.id, w.balance, COALESCE(SUM(e.amount), 0)
+		FROM user_wallet w
+		LEFT JOIN ledger_entry e ON e.wallet_id = w.id
+		GROUP BY w.id, w.balance
+		HAVING w.balance <> COALESCE(SUM(e.amount), 0)
+		ORDER BY w.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reconciliations := []wallet.Reconciliation{}
+	for rows.Next() {
+		var r wallet.Reconciliation
+		if err := rows.Scan(&r.WalletID, &r.Balance, &r.LedgerBalance); err != nil {
+			return nil, err
+		}
+		r.Difference = r.Balance - r.LedgerBalance
+		reconciliations = append(reconciliations, r)
+	}
+	return reconciliations, rows.Err()
+}
+
+// Transactions returns ledger entries for one wallet, newest first.
+func (p *Postgres) Transactions(ctx context.Context, filter wallet.TransactionFilter) ([]wallet.Transaction, error) {
+	var exists bool
+	if err := p.Db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM user_wallet WHERE id = $1)", filter.WalletID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, wallet.ErrWalletNotFound
+	}
+
+	query := `SELECT e.id, e.transaction_id, e.wallet_id, t.kind, t.reference_id, e.amount, e.currency, e.created_at
+		FROM ledger_entry e
+		JOIN ledger_transaction t ON t.id = e.transaction_id
+		WHERE e.wallet_id = $1`
+	args := []any{filter.WalletID}
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		query += fmt.Sprintf(" AND "+condition, len(args))
+	}
+	if filter.BeforeID > 0 {
+		where("e.id < $%d", filter.BeforeID)
+	}
+	if !filter.From.IsZero() {
+		where("e.created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("e.created_at < $%d", filter.To)
+	}
+	if filter.MinAmount != nil {
+		where("ABS(e.amount) >= $%d", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		where("ABS(e.amount) <= $%d", *filter.MaxAmount)
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY e.id DESC LIMIT $%d", len(args))
+
+	rows, err := p.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []wallet.Transaction
+	for rows.Next() {
+		var t wallet.Transaction
+		var referenceID sql.NullInt64
+		if err := rows.Scan(&t.ID, &t.TransactionID, &t.WalletID, &t.Kind, &referenceID, &t.Amount, &t.Currency, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		if referenceID.Valid {
+			id := int(referenceID.Int64)
+			t.ReferenceID = &id
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
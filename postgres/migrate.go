@@ -0,0 +1,237 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/logging"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+//go:embed seed.sql
+var seedSQL string
+
+// migrationLock is the pg_advisory_lock key that serialises migrations
+// when several instances start at once.
+const migrationLock = 7_460_115_001
+
+// Migration is one versioned schema change read from
+// migrations/<version>_<name>.up.sql and its .down.sql counterpart.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrations returns the embedded migrations ordered by version.
+func Migrations() ([]Migration, error) {
+	return loadMigrations(migrationFiles, "migrations")
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		base, direction, ok := cutDirection(entry.Name())
+		if !ok {
+			return nil, fmt.Errorf("migration %s: name must end in .up.sql or .down.sql", entry.Name())
+		}
+		prefix, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a positive version", entry.Name())
+		}
+		body, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d: up and down names differ (%s, %s)", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s: needs both .up.sql and .down.sql", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func cutDirection(file string) (string, string, bool) {
+	if base, ok := strings.CutSuffix(file, ".up.sql"); ok {
+		return base, "up", true
+	}
+	if base, ok := strings.CutSuffix(file, ".down.sql"); ok {
+		return base, "down", true
+	}
+	return "", "", false
+}
+
+// Migrate applies every pending migration, each in its own transaction.
+func (p *Postgres) Migrate(ctx context.Context) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	return p.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if applied[m.Version] {
+				continue
+			}
+			if err := runScript(ctx, conn, m.Up, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.Version, m.Name); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", m.Version, m.Name, err)
+			}
+			logging.FromContext(ctx).Info("applied migration", "version", m.Version, "name", m.Name)
+		}
+		return nil
+	})
+}
+
+// MigrateDown reverts the latest steps applied migrations.
+func (p *Postgres) MigrateDown(ctx context.Context, steps int) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	return p.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			m := migrations[i]
+			if !applied[m.Version] {
+				continue
+			}
+			if err := runScript(ctx, conn, m.Down, "DELETE FROM schema_migrations WHERE version = $1 AND name = $2", m.Version, m.Name); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+			}
+			logging.FromContext(ctx).Info("reverted migration", "version", m.Version, "name", m.Name)
+			steps--
+		}
+		return nil
+	})
+}
+
+// MigrationVersion returns the highest applied migration version, or 0
+// for an empty database.
+func (p *Postgres) MigrationVersion(ctx context.Context) (int, error) {
+	var version int
+	err := p.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		return conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	})
+	return version, err
+}
+
+// Seed loads the sample users and wallets into a database that has no
+// users yet. It runs after Migrate and is meant for development only.
+func (p *Postgres) Seed(ctx context.Context) error {
+	return p.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		var seeded bool
+		if err := conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users)").Scan(&seeded); err != nil {
+			return err
+		}
+		if seeded {
+			return nil
+		}
+		if err := runScript(ctx, conn, seedSQL, ""); err != nil {
+			return err
+		}
+		logging.FromContext(ctx).Info("loaded seed data")
+		return nil
+	})
+}
+
+// withMigrationLock holds a session advisory lock on a single connection
+// while fn runs, so only one instance changes the schema at a time.
+func (p *Postgres) withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := p.Db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLock); err != nil {
+		return err
+	}
+	// unlock even when ctx is cancelled, or the pooled connection keeps the lock
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationLock)
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]bool, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]bool{}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// runScript executes script and the optional bookkeeping statement in
+// one transaction.
+func runScript(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if record != "" {
+		if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
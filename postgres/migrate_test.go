@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/user"
+)
+
+func TestMigrations(t *testing.T) {
+	t.Run("given embedded migrations should pair up and down in version order", func(t *testing.T) {
+		migrations, err := Migrations()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, m := range migrations {
+			if m.Version != i+1 {
+				t.Errorf("expected version %d, got %d_%s", i+1, m.Version, m.Name)
+			}
+		}
+	})
+
+	t.Run("given files out of order should sort by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/0002_b.up.sql":   {Data: []byte("b up")},
+			"m/0002_b.down.sql": {Data: []byte("b down")},
+			"m/0001_a.up.sql":   {Data: []byte("a up")},
+			"m/0001_a.down.sql": {Data: []byte("a down")},
+		}
+
+		migrations, err := loadMigrations(fsys, "m")
+
+		if err != nil || len(migrations) != 2 {
+			t.Fatalf("expected 2 migrations, got %v, %v", migrations, err)
+		}
+		if migrations[0].Name != "a" || migrations[0].Down != "a down" || migrations[1].Up != "b up" {
+			t.Errorf("unexpected migrations %+v", migrations)
+		}
+	})
+
+	t.Run("given invalid files should return error", func(t *testing.T) {
+		cases := map[string]fstest.MapFS{
+			"missing down": {"m/0001_a.up.sql": {Data: []byte("up")}},
+			"bad suffix":   {"m/0001_a.sql": {Data: []byte("up")}},
+			"bad version":  {"m/a_a.up.sql": {Data: []byte("up")}, "m/a_a.down.sql": {Data: []byte("down")}},
+			"name differs": {"m/0001_a.up.sql": {Data: []byte("up")}, "m/0001_b.down.sql": {Data: []byte("down")}},
+		}
+		for name, fsys := range cases {
+			if _, err := loadMigrations(fsys, "m"); err == nil || !strings.HasPrefix(err.Error(), "migration") {
+				t.Errorf("%s: expected migration error, got %v", name, err)
+			}
+		}
+	})
+}
+
+// TestMigrateBaseline upgrades a database created by the init.sql that
+// docker-entrypoint ran before there were migrations. It needs
+// TEST_DB_CONN, like TestStorer.
+func TestMigrateBaseline(t *testing.T) {
+	dsn := os.Getenv("TEST_DB_CONN")
+	if dsn == "" {
+		t.Skip("TEST_DB_CONN is not set")
+	}
+	ctx := context.Background()
+	p := emptyDatabase(t, dsn)
+	baseline, err := os.ReadFile("testdata/init.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Db.ExecContext(ctx, string(baseline)); err != nil {
+		t.Fatalf("load baseline: %v", err)
+	}
+
+	if err := p.Migrate(ctx); err != nil {
+		t.Fatalf("migrate baseline: %v", err)
+	}
+
+	t.Run("given baseline wallets should keep them with their users", func(t *testing.T) {
+		users, err := p.Users(ctx)
+		if err != nil || len(users) != 2 || users[0].Name != "John Doe" || users[1].Name != "Jane Doe" {
+			t.Fatalf("expected John and Jane Doe, got %+v, %v", users, err)
+		}
+		wallets, err := p.WalletByUserId(ctx, "1")
+		if err != nil || len(wallets) != 3 {
+			t.Fatalf("expected John's 3 wallets, got %+v, %v", wallets, err)
+		}
+		for _, w := range wallets {
+			if w.UserName != "John Doe" || w.Currency != "THB" || w.Version != 1 {
+				t.Errorf("expected THB wallet at version 1 for John Doe, got %+v", w)
+			}
+		}
+	})
+
+	t.Run("given baseline balances should open the ledger with them", func(t *testing.T) {
+		reconciliations, err := p.Reconcile(ctx)
+
+		if err != nil || len(reconciliations) != 0 {
+			t.Errorf("expected every wallet to reconcile, got %+v, %v", reconciliations, err)
+		}
+	})
+
+	t.Run("given new user should number it after the backfilled ones", func(t *testing.T) {
+		created, err := p.CreateUser(ctx, user.User{Name: "New User"})
+
+		if err != nil || created.ID != 3 {
+			t.Errorf("expected user 3, got %+v, %v", created, err)
+		}
+	})
+
+	t.Run("given down migrations should return to the baseline schema", func(t *testing.T) {
+		migrations, err := Migrations()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.MigrateDown(ctx, len(migrations)-1); err != nil {
+			t.Fatalf("migrate down: %v", err)
+		}
+
+		var name string
+		if err := p.Db.QueryRowContext(ctx, "SELECT user_name FROM user_wallet WHERE id = 4").Scan(&name); err != nil || name != "Jane Doe" {
+			t.Errorf("expected user_name Jane Doe, got %q, %v", name, err)
+		}
+		if err := p.Migrate(ctx); err != nil {
+			t.Errorf("expected to migrate up again, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,20 @@
+-- The schema the old docker-entrypoint init.sql created, without its sample rows.
+-- Volumes initialised by it already have it, so the statements are guarded and this
+-- migration only records them as version 1. Later migrations evolve it from there.
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'wallet_type') THEN
+		CREATE TYPE wallet_type AS ENUM ('Savings', 'Credit Card', 'Crypto Wallet');
+	END IF;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS user_wallet (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL,
+	user_name VARCHAR(255) NOT NULL,
+	wallet_name VARCHAR(255) NOT NULL,
+	wallet_type wallet_type NOT NULL,
+	balance DECIMAL(10, 2) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
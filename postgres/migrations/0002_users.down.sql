@@ -0,0 +1,9 @@
+DROP INDEX user_wallet_user_id_idx;
+
+ALTER TABLE user_wallet ADD COLUMN user_name VARCHAR(255);
+UPDATE user_wallet w SET user_name = u.name FROM users u WHERE u.id = w.user_id;
+ALTER TABLE user_wallet
+	ALTER COLUMN user_name SET NOT NULL,
+	DROP CONSTRAINT user_wallet_user_id_fkey;
+
+DROP TABLE users;
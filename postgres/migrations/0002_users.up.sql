@@ -0,0 +1,21 @@
+-- Users move out of user_wallet. Every existing user_id becomes a user named
+-- after its latest wallet and created with its first one.
+CREATE TABLE users (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO users (id, name, created_at)
+SELECT user_id, LEFT((array_agg(user_name ORDER BY id DESC))[1], 100), MIN(created_at)
+FROM user_wallet
+GROUP BY user_id;
+
+-- the backfill chose its own ids, so new users continue after them
+SELECT setval(pg_get_serial_sequence('users', 'id'), GREATEST(MAX(id), 0) + 1, false) FROM users;
+
+ALTER TABLE user_wallet
+	ADD CONSTRAINT user_wallet_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id),
+	DROP COLUMN user_name;
+
+CREATE INDEX user_wallet_user_id_idx ON user_wallet (user_id);
@@ -0,0 +1,3 @@
+ALTER TABLE user_wallet
+	DROP COLUMN currency,
+	DROP COLUMN version;
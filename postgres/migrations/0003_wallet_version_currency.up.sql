@@ -0,0 +1,5 @@
+-- version rejects concurrent updates; currency is fixed when a wallet is created.
+-- Existing wallets start at version 1 in THB.
+ALTER TABLE user_wallet
+	ADD COLUMN version INT NOT NULL DEFAULT 1,
+	ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'THB';
@@ -0,0 +1,2 @@
+DROP INDEX user_wallet_created_at_idx;
+DROP INDEX user_wallet_balance_idx;
@@ -0,0 +1,3 @@
+-- Wallet lists are paged by these sort keys, with ties broken by id.
+CREATE INDEX user_wallet_balance_idx ON user_wallet (balance, id);
+CREATE INDEX user_wallet_created_at_idx ON user_wallet (created_at, id);
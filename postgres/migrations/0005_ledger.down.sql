@@ -0,0 +1,3 @@
+DROP TABLE ledger_entry;
+DROP TABLE ledger_transaction;
+DROP TABLE wallet_transfer;
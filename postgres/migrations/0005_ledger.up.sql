@@ -0,0 +1,50 @@
+-- from/to are not foreign keys so transfer history survives wallet deletion
+CREATE TABLE wallet_transfer (
+	id SERIAL PRIMARY KEY,
+	from_wallet_id INT NOT NULL,
+	to_wallet_id INT NOT NULL,
+	amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+	currency CHAR(3) NOT NULL,
+	credit_amount DECIMAL(10, 2) NOT NULL CHECK (credit_amount > 0),
+	credit_currency CHAR(3) NOT NULL,
+	rate NUMERIC(20, 10),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Double-entry ledger: every balance change is a transaction whose entries sum to zero
+-- per currency. Only 'wallet' entries carry a wallet_id; 'external' is money entering or
+-- leaving the system and 'fx' balances each side of a cross-currency transfer.
+CREATE TABLE ledger_transaction (
+	id SERIAL PRIMARY KEY,
+	kind VARCHAR(32) NOT NULL,
+	reference_id INT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE ledger_entry (
+	id SERIAL PRIMARY KEY,
+	transaction_id INT NOT NULL REFERENCES ledger_transaction (id),
+	account VARCHAR(16) NOT NULL DEFAULT 'wallet',
+	wallet_id INT,
+	currency CHAR(3) NOT NULL,
+	amount DECIMAL(12, 2) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX ledger_entry_wallet_id_idx ON ledger_entry (wallet_id, id);
+CREATE INDEX ledger_entry_transaction_id_idx ON ledger_entry (transaction_id);
+
+-- Wallets that predate the ledger open it with their current balances, so they
+-- reconcile. Nothing is posted to an empty database.
+WITH opening AS (
+	INSERT INTO ledger_transaction (kind)
+	SELECT 'opening' WHERE EXISTS (SELECT 1 FROM user_wallet)
+	RETURNING id
+)
+INSERT INTO ledger_entry (transaction_id, account, wallet_id, currency, amount)
+SELECT opening.id, 'wallet', w.id, w.currency, w.balance
+FROM opening, user_wallet w
+UNION ALL
+SELECT opening.id, 'external', NULL, w.currency, -SUM(w.balance)
+FROM opening, user_wallet w
+GROUP BY opening.id, w.currency;
@@ -0,0 +1,12 @@
+-- Responses stored per Idempotency-Key so retried requests replay instead of re-running.
+-- status_code stays NULL while the original request is in flight.
+CREATE TABLE idempotency_key (
+	key VARCHAR(320) PRIMARY KEY,
+	method VARCHAR(10) NOT NULL,
+	path VARCHAR(255) NOT NULL,
+	request_hash CHAR(64) NOT NULL,
+	status_code INT,
+	response_header JSONB,
+	response_body BYTEA,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,10 @@
+-- API keys for service callers. Only the SHA-256 of the key is stored.
+CREATE TABLE api_key (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	prefix VARCHAR(16) NOT NULL,
+	key_hash CHAR(64) NOT NULL UNIQUE,
+	scopes TEXT[] NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	revoked_at TIMESTAMP
+);
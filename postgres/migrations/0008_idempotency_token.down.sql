@@ -0,0 +1 @@
+ALTER TABLE idempotency_key DROP COLUMN token;
@@ -0,0 +1,3 @@
+-- Identifies the reservation holding a key, so a request that outlived its
+-- lease cannot complete or release the key after another request took it over.
+ALTER TABLE idempotency_key ADD COLUMN token CHAR(32);
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+func (p *Postgres) Deposit(ctx context.Context, walletID int, amount wallet.Money) (wallet.Wallet, error) {
+	return p.move(ctx, walletID, amount, ledgerDeposit)
+}
+
+func (p *Postgres) Withdraw(ctx context.Context, walletID int, amount wallet.Money) (wallet.Wallet, error) {
+	return p.move(ctx, walletID, -amount, ledgerWithdrawal)
+}
+
+// move applies delta to the wallet balance server-side, rejecting
+// overdrafts for wallet types that do not allow them.
+func (p *Postgres) move(ctx context.Context, walletID int, delta wallet.Money, kind string) (wallet.Wallet, error) {
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	var walletType, currency string
+	var balance wallet.Money
+	err = tx.QueryRowContext(ctx, "SELECT wallet_type, currency, balance FROM user_wallet WHERE id = $1 FOR UPDATE", walletID).Scan(&walletType, &currency, &balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if !wallet.FitsCurrency(delta, currency) {
+		return wallet.Wallet{}, wallet.ErrAmountPrecision
+	}
+	if balance+delta < 0 && !wallet.CanOverdraft(walletType) {
+		return wallet.Wallet{}, wallet.ErrInsufficientFunds
+	}
+
+	w, err := scanWallet(tx.QueryRowContext(ctx, "UPDATE user_wallet SET balance = balance + $1, version = version + 1 WHERE id = $2 RETURNING "+walletColumns, delta, walletID))
+	if err != nil {
+		return wallet.Wallet{}, mapError(err)
+	}
+
+	if err := postLedger(ctx, tx, kind, nil, walletLeg(walletID, currency, delta), externalLeg(currency, -delta)); err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return wallet.Wallet{}, err
+	}
+	return w, nil
+}
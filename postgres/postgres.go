@@ -2,28 +2,35 @@ package postgres
 
 import (
 	"database/sql"
-	"log"
-	"os"
 
+	"github.com/KKGo-Software-engineering/fun-exercise-api/config"
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 )
 
 type Postgres struct {
 	Db *sql.DB
 }
 
-func New() (*Postgres, error) {
-	//replace connection string with ENV
-
-	databaseSource := os.Getenv("DB_CONN")
-	db, err := sql.Open("postgres", databaseSource)
+// New opens the connection pool without connecting, so the server can
+// start and report itself unready while the database is unreachable.
+// Every statement is traced through the global tracer provider.
+func New(cfg config.Database) (*Postgres, error) {
+	db, err := otelsql.Open("postgres", cfg.DSN,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{DisableErrSkip: true, OmitConnResetSession: true, OmitRows: true}),
+	)
 	if err != nil {
-		log.Fatal(err)
 		return nil, err
 	}
-	err = db.Ping()
-	if err != nil {
-		log.Fatal(err)
-	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 	return &Postgres{Db: db}, nil
 }
+
+// Close waits for running queries to finish and closes the pool.
+func (p *Postgres) Close() error {
+	return p.Db.Close()
+}
@@ -0,0 +1,19 @@
+-- Sample data for local development and integration tests, applied when DB_SEED=true
+-- and the database has no users yet.
+INSERT INTO users (name) VALUES
+('John Doe'),
+('Jane Doe');
+
+INSERT INTO user_wallet (user_id, wallet_name, wallet_type, balance) VALUES
+(1, 'John Savings', 'Savings', 1000.00),
+(1, 'John Credit Card', 'Credit Card', 500.00),
+(1, 'John Crypto Wallet', 'Crypto Wallet', 100.00),
+(2, 'Jane Savings', 'Savings', 2000.00),
+(2, 'Jane Credit Card', 'Credit Card', 1000.00),
+(2, 'Jane Crypto Wallet', 'Crypto Wallet', 200.00);
+
+INSERT INTO ledger_transaction (kind) VALUES ('opening');
+INSERT INTO ledger_entry (transaction_id, account, wallet_id, currency, amount)
+SELECT currval('ledger_transaction_id_seq'), 'wallet', id, currency, balance FROM user_wallet;
+INSERT INTO ledger_entry (transaction_id, account, wallet_id, currency, amount)
+SELECT currval('ledger_transaction_id_seq'), 'external', NULL, currency, -SUM(balance) FROM user_wallet GROUP BY currency;
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/config"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/user"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet/wallettest"
+	"github.com/lib/pq"
+)
+
+// TestStorer runs the contract suite against a real server. Point
+// TEST_DB_CONN at any Postgres, e.g. the one in docker-compose.yaml; each
+// subtest gets its own database and drops it afterwards, so existing data
+// is left alone.
+func TestStorer(t *testing.T) {
+	dsn := os.Getenv("TEST_DB_CONN")
+	if dsn == "" {
+		t.Skip("TEST_DB_CONN is not set")
+	}
+	wallettest.Run(t, func(t *testing.T) (wallet.Storer, [2]int) {
+		p := testDatabase(t, dsn)
+		john, err := p.CreateUser(context.Background(), user.User{Name: "John Doe"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		jane, err := p.CreateUser(context.Background(), user.User{Name: "Jane Doe"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p, [2]int{john.ID, jane.ID}
+	})
+}
+
+// testDatabase creates a migrated, empty database on the server dsn
+// points at and drops it when t finishes.
+func testDatabase(t *testing.T, dsn string) *Postgres {
+	t.Helper()
+	p := emptyDatabase(t, dsn)
+	if err := p.Migrate(context.Background()); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	return p
+}
+
+// emptyDatabase creates a database without any schema on the server dsn
+// points at and drops it when t finishes.
+func emptyDatabase(t *testing.T, dsn string) *Postgres {
+	t.Helper()
+	ctx := context.Background()
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		if dsn, err = pq.ParseURL(dsn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("wallet_test_%d_%d", os.Getpid(), time.Now().UnixNano())
+	if _, err := admin.ExecContext(ctx, "CREATE DATABASE "+name); err != nil {
+		admin.Close()
+		t.Fatalf("create test database: %v", err)
+	}
+
+	// later keys win, so this points the pool at the new database
+	p, err := New(config.Database{DSN: dsn + " dbname=" + name, MaxOpenConns: 10, MaxIdleConns: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		p.Close()
+		admin.ExecContext(ctx, "DROP DATABASE IF EXISTS "+name)
+		admin.Close()
+	})
+	return p
+}
@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/KKGo-Software-engineering/fun-exercise-api/postgres")
+
+// startSpan starts an internal span for a store method. Each statement it
+// runs gets a client child span from the otelsql driver wrapper. Methods
+// that run a single statement pass it so the method span reads on its own;
+// methods that run several pass "" and leave them to the child spans.
+func startSpan(ctx context.Context, method, statement string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{semconv.DBSystemPostgreSQL}
+	if statement != "" {
+		attrs = append(attrs, semconv.DBStatement(statement))
+	}
+	return tracer.Start(ctx, "postgres."+method,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// endSpan records *err on span and ends it. Pass the method's named
+// error result so it is read when the method returns.
+func endSpan(span trace.Span, err *error) {
+	if *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
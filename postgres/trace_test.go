@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	query := func(fail error) (err error) {
+		_, span := startSpan(context.Background(), "WalletByUserId", "SELECT * FROM user_wallet WHERE user_id = $1")
+		defer endSpan(span, &err)
+		return fail
+	}
+
+	query(nil)
+	query(errors.New("connection refused"))
+	_, span := startSpan(context.Background(), "DeleteWallet", "")
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	for _, attr := range spans[2].Attributes() {
+		if attr.Key == semconv.DBStatementKey {
+			t.Errorf("expected no db.statement without a single statement, got %v", spans[2].Attributes())
+		}
+	}
+	for _, span := range spans {
+		if span.SpanKind() != trace.SpanKindInternal {
+			t.Errorf("expected an internal span, got %v", span.SpanKind())
+		}
+	}
+	for _, span := range spans[:2] {
+		if span.Name() != "postgres.WalletByUserId" {
+			t.Errorf("expected span postgres.WalletByUserId, got %s", span.Name())
+		}
+		found := false
+		for _, attr := range span.Attributes() {
+			if attr == semconv.DBStatement("SELECT * FROM user_wallet WHERE user_id = $1") {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected db.statement attribute, got %v", span.Attributes())
+		}
+	}
+	if spans[0].Status().Code != codes.Unset {
+		t.Errorf("expected unset status on success, got %v", spans[0].Status())
+	}
+	if spans[1].Status().Code != codes.Error || len(spans[1].Events()) != 1 {
+		t.Errorf("expected error status and recorded error, got %v and %v", spans[1].Status(), spans[1].Events())
+	}
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+type lockedWallet struct {
+	WalletType string
+	Currency   string
+	Balance    wallet.Money
+}
+
+// Transfer moves money between two wallets in a single transaction.
+// Both rows are locked in id order so concurrent transfers between the
+// same pair of wallets cannot deadlock. The caller quotes the transfer:
+// Amount is debited in Currency and CreditAmount credited in CreditCurrency.
+func (p *Postgres) Transfer(ctx context.Context, t wallet.Transfer) (wallet.Transfer, error) {
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return wallet.Transfer{}, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, wallet_type, currency, balance FROM user_wallet WHERE id IN ($1, $2) ORDER BY id FOR UPDATE", t.FromWalletID, t.ToWalletID)
+	if err != nil {
+		return wallet.Transfer{}, err
+	}
+	locked := map[int]lockedWallet{}
+	for rows.Next() {
+		var id int
+		var w lockedWallet
+		if err := rows.Scan(&id, &w.WalletType, &w.Currency, &w.Balance); err != nil {
+			rows.Close()
+			return wallet.Transfer{}, err
+		}
+		locked[id] = w
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return wallet.Transfer{}, err
+	}
+
+	from, ok := locked[t.FromWalletID]
+	if !ok {
+		return wallet.Transfer{}, wallet.ErrWalletNotFound
+	}
+	to, ok := locked[t.ToWalletID]
+	if !ok {
+		return wallet.Transfer{}, wallet.ErrWalletNotFound
+	}
+	if from.Currency != t.Currency || to.Currency != t.CreditCurrency {
+		return wallet.Transfer{}, fmt.Errorf("transfer quoted %s to %s but wallets hold %s and %s", t.Currency, t.CreditCurrency, from.Currency, to.Currency)
+	}
+	if from.Balance < t.Amount && !wallet.CanOverdraft(from.WalletType) {
+		return wallet.Transfer{}, wallet.ErrInsufficientFunds
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE user_wallet SET balance = balance - $1, version = version + 1 WHERE id = $2", t.Amount, t.FromWalletID); err != nil {
+		return wallet.Transfer{}, mapError(err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE user_wallet SET balance = balance + $1, version = version + 1 WHERE id = $2", t.CreditAmount, t.ToWalletID); err != nil {
+		return wallet.Transfer{}, mapError(err)
+	}
+
+	var rate *string
+	if t.Rate != "" {
+		rate = &t.Rate
+	}
+	err = tx.QueryRowContext(ctx, `INSERT INTO wallet_transfer (from_wallet_id, to_wallet_id, amount, currency, credit_amount, credit_currency, rate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		t.FromWalletID, t.ToWalletID, t.Amount, t.Currency, t.CreditAmount, t.CreditCurrency, rate,
+	).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return wallet.Transfer{}, mapError(err)
+	}
+
+	entries := []entry{
+		walletLeg(t.FromWalletID, t.Currency, -t.Amount),
+		walletLeg(t.ToWalletID, t.CreditCurrency, t.CreditAmount),
+	}
+	if t.Currency != t.CreditCurrency {
+		entries = append(entries, fxLeg(t.Currency, t.Amount), fxLeg(t.CreditCurrency, -t.CreditAmount))
+	}
+	if err := postLedger(ctx, tx, ledgerTransfer, &t.ID, entries...); err != nil {
+		return wallet.Transfer{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return wallet.Transfer{}, err
+	}
+	return t, nil
+}
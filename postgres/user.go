@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/user"
+)
+
+const userColumns = "id, name, created_at"
+
+func scanUser(row scanner) (user.User, error) {
+	var u user.User
+	err := row.Scan(&u.ID, &u.Name, &u.CreatedAt)
+	return u, err
+}
+
+func (p *Postgres) Users(ctx context.Context) ([]user.User, error) {
+	rows, err := p.Db.QueryContext(ctx, "SELECT "+userColumns+" FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []user.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (p *Postgres) UserById(ctx context.Context, id int) (user.User, error) {
+	u, err := scanUser(p.Db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return user.User{}, user.ErrUserNotFound
+	}
+	return u, err
+}
+
+func (p *Postgres) CreateUser(ctx context.Context, u user.User) (user.User, error) {
+	created, err := scanUser(p.Db.QueryRowContext(ctx, "INSERT INTO users (name) VALUES ($1) RETURNING "+userColumns, u.Name))
+	if err != nil {
+		return user.User{}, mapError(err)
+	}
+	return created, nil
+}
+
+func (p *Postgres) UpdateUser(ctx context.Context, u user.User) (user.User, error) {
+	updated, err := scanUser(p.Db.QueryRowContext(ctx, "UPDATE users SET name = $1 WHERE id = $2 RETURNING "+userColumns, u.Name, u.ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return user.User{}, user.ErrUserNotFound
+	}
+	if err != nil {
+		return user.User{}, mapError(err)
+	}
+	return updated, nil
+}
@@ -1,140 +1,346 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/KKGo-Software-engineering/fun-exercise-api/logging"
 	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
 )
 
 type Wallet struct {
-	ID         int       `postgres:"id"`
-	UserID     int       `postgres:"user_id"`
-	UserName   string    `postgres:"user_name"`
-	WalletName string    `postgres:"wallet_name"`
-	WalletType string    `postgres:"wallet_type"`
-	Balance    float64   `postgres:"balance"`
-	CreatedAt  time.Time `postgres:"created_at"`
+	ID         int          `postgres:"id"`
+	UserID     int          `postgres:"user_id"`
+	UserName   string       `postgres:"user_name"`
+	WalletName string       `postgres:"wallet_name"`
+	WalletType string       `postgres:"wallet_type"`
+	Balance    wallet.Money `postgres:"balance"`
+	CreatedAt  time.Time    `postgres:"created_at"`
+	Version    int          `postgres:"version"`
+	Currency   string       `postgres:"currency"`
 }
 
-func (p *Postgres) Wallets(walletType string) ([]wallet.Wallet, error) {
+// walletColumns reads user_name from users so it works in RETURNING too.
+const walletColumns = "id, user_id, (SELECT name FROM users WHERE users.id = user_wallet.user_id) AS user_name, wallet_name, wallet_type, balance, created_at, version, currency"
 
-	var rows *sql.Rows
-	var err error
-	if walletType != "" {
-		rows, err = p.Db.Query("SELECT * FROM user_wallet WHERE wallet_type = $1", walletType)
-	} else {
-		rows, err = p.Db.Query("SELECT * FROM user_wallet")
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanWallet reads a row selected with walletColumns.
+func scanWallet(row scanner) (wallet.Wallet, error) {
+	var w Wallet
+	err := row.Scan(&w.ID,
+		&w.UserID, &w.UserName,
+		&w.WalletName, &w.WalletType,
+		&w.Balance, &w.CreatedAt,
+		&w.Version, &w.Currency,
+	)
+	if err != nil {
+		return wallet.Wallet{}, err
 	}
+	return wallet.Wallet{
+		ID:         w.ID,
+		UserID:     w.UserID,
+		UserName:   w.UserName,
+		WalletName: w.WalletName,
+		WalletType: w.WalletType,
+		Balance:    w.Balance,
+		CreatedAt:  w.CreatedAt,
+		Version:    w.Version,
+		Currency:   w.Currency,
+	}, nil
+}
 
+// Wallets pages with a keyset on (sort column, id), so the cursor stays
+// valid while wallets are added or removed.
+func (p *Postgres) Wallets(ctx context.Context, filter wallet.WalletFilter) (_ []wallet.Wallet, err error) {
+	query := "SELECT " + walletColumns + " FROM user_wallet WHERE TRUE"
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		query += fmt.Sprintf(" AND "+condition, len(args))
+	}
+	if filter.WalletType != "" {
+		where("wallet_type = $%d", filter.WalletType)
+	}
+	if filter.UserID > 0 {
+		where("user_id = $%d", filter.UserID)
+	}
+	if filter.MinBalance != nil {
+		where("balance >= $%d", *filter.MinBalance)
+	}
+	if filter.MaxBalance != nil {
+		where("balance <= $%d", *filter.MaxBalance)
+	}
+	if !filter.From.IsZero() {
+		where("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("created_at < $%d", filter.To)
+	}
+
+	column, direction, compare := "id", "ASC", ">"
+	switch filter.Sort {
+	case wallet.SortBalance, wallet.SortCreatedAt:
+		column = filter.Sort
+	}
+	if filter.Desc {
+		direction, compare = "DESC", "<"
+	}
+	if after := filter.After; after != nil {
+		switch column {
+		case wallet.SortBalance:
+			args = append(args, after.Balance, after.ID)
+		case wallet.SortCreatedAt:
+			args = append(args, after.CreatedAt, after.ID)
+		default:
+			args = append(args, after.ID)
+		}
+		if column == "id" {
+			query += fmt.Sprintf(" AND id %s $%d", compare, len(args))
+		} else {
+			query += fmt.Sprintf(" AND (%s, id) %s ($%d, $%d)", column, compare, len(args)-1, len(args))
+		}
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s", column, direction, direction)
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	ctx, span := startSpan(ctx, "Wallets", query)
+	defer endSpan(span, &err)
+
+	rows, err := p.Db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 	defer rows.Close()
 
 	var wallets []wallet.Wallet
 	for rows.Next() {
-		var w Wallet
-		err := rows.Scan(&w.ID,
-			&w.UserID, &w.UserName,
-			&w.WalletName, &w.WalletType,
-			&w.Balance, &w.CreatedAt,
-		)
+		w, err := scanWallet(rows)
 		if err != nil {
 			return nil, err
 		}
-		wallets = append(wallets, wallet.Wallet{
-			ID:         w.ID,
-			UserID:     w.UserID,
-			UserName:   w.UserName,
-			WalletName: w.WalletName,
-			WalletType: w.WalletType,
-			Balance:    w.Balance,
-			CreatedAt:  w.CreatedAt,
-		})
-	}
-	return wallets, nil
+		wallets = append(wallets, w)
+	}
+	return wallets, rows.Err()
 }
 
-func (p *Postgres) CreateWallet(wallet wallet.Wallet) (int, error) {
-	// _, err := p.Db.Exec("INSERT INTO user_wallet (user_id, user_name, wallet_name, wallet_type, balance) VALUES ($1, $2, $3, $4, $5)",
-	// 	wallet.UserID, wallet.UserName, wallet.WalletName, wallet.WalletType, wallet.Balance,
-	// )
-	//Get Last Inserted ID
-	var id int
-	err := p.Db.QueryRow("INSERT INTO user_wallet (user_id, user_name, wallet_name, wallet_type, balance) VALUES ($1, $2, $3, $4, $5) RETURNING id", wallet.UserID, wallet.UserName, wallet.WalletName, wallet.WalletType, wallet.Balance).Scan(&id)
+func (p *Postgres) CreateWallet(ctx context.Context, w wallet.Wallet) (_ wallet.Wallet, err error) {
+	query := "INSERT INTO user_wallet (user_id, wallet_name, wallet_type, balance, currency) VALUES ($1, $2, $3, $4, $5) RETURNING " + walletColumns
+	ctx, span := startSpan(ctx, "CreateWallet", "")
+	defer endSpan(span, &err)
+
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	created, err := scanWallet(tx.QueryRowContext(ctx, query,
+		w.UserID, w.WalletName, w.WalletType, w.Balance, w.Currency,
+	))
 	if err != nil {
-		return -1, err
+		return wallet.Wallet{}, mapError(err)
 	}
 
-	return id, err
+	if created.Balance != 0 {
+		if err := postLedger(ctx, tx, ledgerOpening, nil, walletLeg(created.ID, created.Currency, created.Balance), externalLeg(created.Currency, -created.Balance)); err != nil {
+			return wallet.Wallet{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return wallet.Wallet{}, err
+	}
+	return created, nil
 }
 
-func (p *Postgres) UpdateWallet(wallet wallet.Wallet) error {
-	_, err := p.Db.Exec("UPDATE user_wallet SET user_id = $1, user_name = $2, wallet_name = $3, wallet_type = $4, balance = $5 WHERE id = $6",
-		wallet.UserID, wallet.UserName, wallet.WalletName, wallet.WalletType, wallet.Balance, wallet.ID,
-	)
+// UpdateWallet changes the owner, name and type of a wallet and ignores
+// w.Balance: balances only move through deposits, withdrawals and
+// transfers so they always match the ledger. Version is required and must
+// match the stored version, otherwise wallet.ErrVersionRequired or
+// wallet.ErrVersionConflict is returned.
+func (p *Postgres) UpdateWallet(ctx context.Context, w wallet.Wallet) (_ wallet.Wallet, err error) {
+	query := "UPDATE user_wallet SET user_id = $1, wallet_name = $2, wallet_type = $3, version = version + 1 WHERE id = $4 RETURNING " + walletColumns
+	ctx, span := startSpan(ctx, "UpdateWallet", "")
+	defer endSpan(span, &err)
+
+	if w.Version == 0 {
+		return wallet.Wallet{}, wallet.ErrVersionRequired
+	}
+	tx, err := p.Db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return wallet.Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	var version int
+	var currency string
+	err = tx.QueryRowContext(ctx, "SELECT version, currency FROM user_wallet WHERE id = $1 FOR UPDATE", w.ID).Scan(&version, &currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if w.Version != version {
+		logging.FromContext(ctx).Debug("wallet version conflict", "wallet_id", w.ID, "version", w.Version, "stored_version", version)
+		return wallet.Wallet{}, wallet.ErrVersionConflict
+	}
+	if w.Currency != "" && w.Currency != currency {
+		return wallet.Wallet{}, wallet.ErrCurrencyChange
+	}
+
+	updated, err := scanWallet(tx.QueryRowContext(ctx, query,
+		w.UserID, w.WalletName, w.WalletType, w.ID,
+	))
+	if err != nil {
+		return wallet.Wallet{}, mapError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return wallet.Wallet{}, err
 	}
-	return nil
+	return updated, nil
 }
 
-func (p *Postgres) DeleteWalletByUserId(userId string) error {
+func (p *Postgres) DeleteWalletByUserId(ctx context.Context, userId string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteWalletByUserId", "")
+	defer endSpan(span, &err)
 
-	isExist, errChk := CheckWalletByUserId(p, userId)
-	if errChk != nil {
-		return errChk
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	if !isExist {
-		return errors.New("Wallet not found for user id: " + userId)
+	deleted, err := closeWallets(ctx, tx, "user_id = $1", userId)
+	if err != nil {
+		return mapError(err)
+	}
+	if deleted == 0 {
+		return wallet.NotFound("no wallets found for user id " + userId)
 	}
+	return tx.Commit()
+}
+
+func (p *Postgres) DeleteWallet(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "DeleteWallet", "")
+	defer endSpan(span, &err)
 
-	_, err := p.Db.Exec("DELETE FROM user_wallet WHERE user_id = $1", userId)
+	tx, err := p.Db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	return nil
+	defer tx.Rollback()
+
+	deleted, err := closeWallets(ctx, tx, "id = $1", id)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return wallet.ErrWalletNotFound
+	}
+	return tx.Commit()
 }
 
-func CheckWalletByUserId(p *Postgres, userId string) (bool, error) {
-	var id int
-	err := p.Db.QueryRow("SELECT id FROM user_wallet WHERE user_id = $1", userId).Scan(&id)
+// closeWallets deletes the wallets matching where, first booking their
+// remaining balances out to the external account so the ledger still
+// balances after the rows are gone.
+func closeWallets(ctx context.Context, tx *sql.Tx, where string, arg any) (int, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id, currency, balance FROM user_wallet WHERE "+where+" FOR UPDATE", arg)
+	if err != nil {
+		return 0, err
+	}
+	var closing []entry
+	for rows.Next() {
+		var id int
+		var currency string
+		var balance wallet.Money
+		if err := rows.Scan(&id, &currency, &balance); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if balance != 0 {
+			closing = append(closing, walletLeg(id, currency, -balance), externalLeg(currency, balance))
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(closing) > 0 {
+		if err := postLedger(ctx, tx, ledgerClosing, nil, closing...); err != nil {
+			return 0, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM user_wallet WHERE "+where, arg)
 	if err != nil {
-		return false, err
+		return 0, err
 	}
-	return true, nil
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
 }
 
-func (p *Postgres) WalletByUserId(userId string) ([]wallet.Wallet, error) {
-	rows, err := p.Db.Query("SELECT * FROM user_wallet WHERE user_id = $1", userId)
+func (p *Postgres) WalletByUserId(ctx context.Context, userId string) (_ []wallet.Wallet, err error) {
+	query := "SELECT " + walletColumns + " FROM user_wallet WHERE user_id = $1 ORDER BY id"
+	ctx, span := startSpan(ctx, "WalletByUserId", query)
+	defer endSpan(span, &err)
+
+	rows, err := p.Db.QueryContext(ctx, query, userId)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 	defer rows.Close()
 
 	var wallets []wallet.Wallet
 	for rows.Next() {
-		var w Wallet
-		err := rows.Scan(&w.ID,
-			&w.UserID, &w.UserName,
-			&w.WalletName, &w.WalletType,
-			&w.Balance, &w.CreatedAt,
-		)
+		w, err := scanWallet(rows)
 		if err != nil {
 			return nil, err
 		}
-		wallets = append(wallets, wallet.Wallet{
-			ID:         w.ID,
-			UserID:     w.UserID,
-			UserName:   w.UserName,
-			WalletName: w.WalletName,
-			WalletType: w.WalletType,
-			Balance:    w.Balance,
-			CreatedAt:  w.CreatedAt,
-		})
-	}
-	return wallets, nil
+		wallets = append(wallets, w)
+	}
+	return wallets, rows.Err()
+}
+
+func (p *Postgres) WalletById(ctx context.Context, id int) (_ wallet.Wallet, err error) {
+	query := "SELECT " + walletColumns + " FROM user_wallet WHERE id = $1"
+	ctx, span := startSpan(ctx, "WalletById", query)
+	defer endSpan(span, &err)
+
+	w, err := scanWallet(p.Db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	return w, err
+}
+
+// WalletTotals counts wallets and sums their balances by type and currency.
+func (p *Postgres) WalletTotals(ctx context.Context) (_ []wallet.WalletTotal, err error) {
+	query := "SELECT wallet_type, currency, COUNT(*), COALESCE(SUM(balance), 0) FROM user_wallet GROUP BY wallet_type, currency ORDER BY wallet_type, currency"
+	ctx, span := startSpan(ctx, "WalletTotals", query)
+	defer endSpan(span, &err)
+
+	rows, err := p.Db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []wallet.WalletTotal
+	for rows.Next() {
+		var t wallet.WalletTotal
+		if err := rows.Scan(&t.WalletType, &t.Currency, &t.Wallets, &t.Balance); err != nil {
+			return nil, err
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
 }
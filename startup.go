@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/config"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/memory"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/postgres"
+)
+
+// databaseRetry is how often startup pings a database that is not up yet.
+const databaseRetry = 2 * time.Second
+
+// startup waits for Postgres and then applies migrations and seed data.
+// It runs while the server already answers /healthz and /readyz, so a
+// slow database shows up as "starting" rather than as a dead process.
+// The in-memory store only needs seeding.
+func startup(ctx context.Context, cfg config.Config, store Store, logger *slog.Logger) error {
+	m, ok := store.(*memory.Memory)
+	if ok {
+		if cfg.Features.Seed {
+			m.Seed()
+		}
+		return nil
+	}
+
+	p := store.(*postgres.Postgres)
+	for {
+		err := p.Db.PingContext(ctx)
+		if err == nil {
+			break
+		}
+		logger.Warn("waiting for postgres", "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(databaseRetry):
+		}
+	}
+
+	if cfg.Features.AutoMigrate {
+		if err := p.Migrate(ctx); err != nil {
+			return err
+		}
+	}
+	if cfg.Features.Seed {
+		if err := p.Seed(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/KKGo-Software-engineering/fun-exercise-api/apikey"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/config"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/idempotency"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/memory"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/metrics"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/postgres"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/user"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+// Store is everything the API needs from a storage backend.
+type Store interface {
+	wallet.Storer
+	user.Storer
+	apikey.Storer
+	idempotency.Storer
+	metrics.Totals
+}
+
+func openStore(cfg config.Database) (Store, error) {
+	if cfg.Driver == config.DriverMemory {
+		return memory.New(), nil
+	}
+	return postgres.New(cfg)
+}
@@ -0,0 +1,69 @@
+// Package tracing installs the OpenTelemetry tracer provider that the
+// Echo middleware and the postgres package report spans to.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// ServiceName identifies the API in traces.
+const ServiceName = "wallet-api"
+
+// Setup installs a global tracer provider exporting to the configured
+// exporter and accepts W3C trace context from callers. With TracingNone
+// nothing is installed and spans cost next to nothing. The returned
+// function flushes pending spans and must be called before exiting.
+func Setup(ctx context.Context, cfg config.Tracing, version string) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	var err error
+	switch cfg.Exporter {
+	case config.TracingStdout:
+		var w io.Writer = os.Stdout
+		if cfg.File != "" {
+			if file, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+				return nil, err
+			}
+			w = file
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case config.TracingOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return func(context.Context) error { return nil }, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			err = errors.Join(err, file.Close())
+		}
+		return err
+	}, nil
+}
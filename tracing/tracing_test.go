@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/config"
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup(t *testing.T) {
+	t.Run("given stdout exporter with a file should write spans to it", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "spans.json")
+
+		shutdown, err := Setup(context.Background(), config.Tracing{Exporter: config.TracingStdout, File: path}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, span := otel.Tracer("test").Start(context.Background(), "GET /api/v1/users/:id/wallets")
+		span.End()
+		if err := shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		out, _ := os.ReadFile(path)
+		for _, want := range []string{"GET /api/v1/users/:id/wallets", ServiceName} {
+			if !strings.Contains(string(out), want) {
+				t.Errorf("expected %q in exported spans, got %s", want, out)
+			}
+		}
+	})
+
+	t.Run("given no exporter should return a no-op shutdown", func(t *testing.T) {
+		shutdown, err := Setup(context.Background(), config.Tracing{Exporter: config.TracingNone}, "test")
+
+		if err != nil || shutdown(context.Background()) != nil {
+			t.Errorf("expected no-op setup, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package user
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/auth"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+	"github.com/labstack/echo/v4"
+)
+
+type Handler struct {
+	store Storer
+}
+
+func New(db Storer) *Handler {
+	return &Handler{store: db}
+}
+
+var errForbidden = wallet.Forbidden("cannot access another user")
+
+func principal(c echo.Context) (auth.Principal, error) {
+	p, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return auth.Principal{}, echo.ErrUnauthorized
+	}
+	return p, nil
+}
+
+// UsersHandler
+//
+//	@Summary		Get users
+//	@Description	List users. Callers without the admin role only see themselves.
+//	@Tags			user
+//	@Produce		json
+//	@Success		200	{array}	User
+//	@Failure		401	{object}	wallet.Err
+//	@Failure		500	{object}	wallet.Err
+//	@Security		BearerAuth
+//	@Security		ApiKeyAuth
+//	@Router			/api/v1/users [get]
+func (h *Handler) UsersHandler(c echo.Context) error {
+	p, err := principal(c)
+	if err != nil {
+		return err
+	}
+	if !p.IsAdmin() && !p.IsService() {
+		self, err := h.store.UserById(c.Request().Context(), p.UserID)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, []User{self})
+	}
+
+	users, err := h.store.Users(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	if users == nil {
+		users = []User{}
+	}
+	return c.JSON(http.StatusOK, users)
+}
+
+// CreateUserHandler
+//
+//	@Summary		Create user
+//	@Description	Create a user. Requires the admin role.
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			user	body	User	true	"User object"
+//	@Param			Idempotency-Key	header	string	false	"replays the stored response when retried with the same key"
+//	@Success		201	{object}	User
+//	@Failure		400	{object}	wallet.Err
+//	@Failure		401	{object}	wallet.Err
+//	@Failure		403	{object}	wallet.Err
+//	@Failure		500	{object}	wallet.Err
+//	@Security		BearerAuth
+//	@Security		ApiKeyAuth
+//	@Router			/api/v1/users [post]
+func (h *Handler) CreateUserHandler(c echo.Context) error {
+	p, err := principal(c)
+	if err != nil {
+		return err
+	}
+	if !p.IsAdmin() && !p.IsService() {
+		return errForbidden
+	}
+
+	var user User
+	if err := c.Bind(&user); err != nil {
+		return err
+	}
+	if err := c.Validate(&user); err != nil {
+		return err
+	}
+	user.Name = strings.TrimSpace(user.Name)
+
+	created, err := h.store.CreateUser(c.Request().Context(), user)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, created)
+}
+
+// UpdateUserHandler
+//
+//	@Summary		Update user
+//	@Description	Rename a user. The new name shows on all of the user's wallets.
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			user	body	User	true	"User object"
+//	@Param			Idempotency-Key	header	string	false	"replays the stored response when retried with the same key"
+//	@Success		200	{object}	User
+//	@Failure		400	{object}	wallet.Err
+//	@Failure		401	{object}	wallet.Err
+//	@Failure		403	{object}	wallet.Err
+//	@Failure		404	{object}	wallet.Err
+//	@Failure		500	{object}	wallet.Err
+//	@Security		BearerAuth
+//	@Security		ApiKeyAuth
+//	@Router			/api/v1/users [put]
+func (h *Handler) UpdateUserHandler(c echo.Context) error {
+	var user User
+	if err := c.Bind(&user); err != nil {
+		return err
+	}
+	if user.ID <= 0 {
+		return wallet.NewValidationError("id", "is required")
+	}
+	if err := c.Validate(&user); err != nil {
+		return err
+	}
+
+	p, err := principal(c)
+	if err != nil {
+		return err
+	}
+	if !p.IsAdmin() && !p.IsService() && p.UserID != user.ID {
+		return errForbidden
+	}
+	user.Name = strings.TrimSpace(user.Name)
+
+	updated, err := h.store.UpdateUser(c.Request().Context(), user)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, updated)
+}
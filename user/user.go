@@ -0,0 +1,26 @@
+// Package user manages the users that own wallets.
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+var ErrUserNotFound = wallet.NotFound("user not found")
+
+type User struct {
+	ID        int       `json:"id" example:"1"`
+	Name      string    `json:"name" example:"John Doe" validate:"required,notblank,max=100"`
+	CreatedAt time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+type Storer interface {
+	Users(ctx context.Context) ([]User, error)
+	// UserById returns ErrUserNotFound when the user does not exist.
+	UserById(ctx context.Context, id int) (User, error)
+	CreateUser(ctx context.Context, user User) (User, error)
+	// UpdateUser returns ErrUserNotFound when the user does not exist.
+	UpdateUser(ctx context.Context, user User) (User, error)
+}
@@ -0,0 +1,186 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/auth"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+	"github.com/labstack/echo/v4"
+)
+
+type StubUser struct {
+	users []User
+	err   error
+}
+
+func (s StubUser) Users(ctx context.Context) ([]User, error) {
+	return s.users, s.err
+}
+
+func (s StubUser) UserById(ctx context.Context, id int) (User, error) {
+	for _, u := range s.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return User{}, ErrUserNotFound
+}
+
+func (s StubUser) CreateUser(ctx context.Context, user User) (User, error) {
+	user.ID = len(s.users) + 1
+	return user, s.err
+}
+
+func (s StubUser) UpdateUser(ctx context.Context, user User) (User, error) {
+	if _, err := s.UserById(ctx, user.ID); err != nil {
+		return User{}, err
+	}
+	return user, s.err
+}
+
+var (
+	admin = auth.Principal{UserID: 1, Roles: []string{auth.RoleAdmin}}
+	users = []User{{ID: 1, Name: "John Doe"}, {ID: 2, Name: "Jane Doe"}}
+)
+
+func newContext(method, body string, p auth.Principal) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	e.Validator = wallet.NewValidator()
+	req := httptest.NewRequest(method, "/api/v1/users", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetPrincipal(c, p)
+	return c, rec
+}
+
+func serve(c echo.Context, h echo.HandlerFunc) {
+	if err := h(c); err != nil {
+		wallet.ErrorHandler(err, c)
+	}
+}
+
+func TestGetUsers(t *testing.T) {
+	t.Run("given admin should return all users", func(t *testing.T) {
+		c, rec := newContext(http.MethodGet, "", admin)
+
+		serve(c, New(StubUser{users: users}).UsersHandler)
+
+		var got []User
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("expected list of users, got %s", rec.Body.String())
+		}
+		if !reflect.DeepEqual(users, got) {
+			t.Errorf("expected %v, got %v", users, got)
+		}
+	})
+
+	t.Run("given user should return only themselves", func(t *testing.T) {
+		c, rec := newContext(http.MethodGet, "", auth.Principal{UserID: 2})
+
+		serve(c, New(StubUser{users: users}).UsersHandler)
+
+		var got []User
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("expected list of users, got %s", rec.Body.String())
+		}
+		if len(got) != 1 || got[0].ID != 2 {
+			t.Errorf("expected only user 2, got %v", got)
+		}
+	})
+
+	t.Run("given unable to get users should return 500", func(t *testing.T) {
+		c, rec := newContext(http.MethodGet, "", admin)
+
+		serve(c, New(StubUser{err: errors.New("unable to get users")}).UsersHandler)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected 500, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestCreateUser(t *testing.T) {
+	t.Run("given admin should create user", func(t *testing.T) {
+		c, rec := newContext(http.MethodPost, `{"name": " Jim Doe "}`, admin)
+
+		serve(c, New(StubUser{users: users}).CreateUserHandler)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d and %s", rec.Code, rec.Body.String())
+		}
+		var got User
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || got.Name != "Jim Doe" {
+			t.Errorf("expected Jim Doe, got %s", rec.Body.String())
+		}
+	})
+
+	t.Run("given blank name should return 400", func(t *testing.T) {
+		c, rec := newContext(http.MethodPost, `{"name": "  "}`, admin)
+
+		serve(c, New(StubUser{}).CreateUserHandler)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given non admin user should return 403", func(t *testing.T) {
+		c, rec := newContext(http.MethodPost, `{"name": "Jim Doe"}`, auth.Principal{UserID: 2})
+
+		serve(c, New(StubUser{}).CreateUserHandler)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestUpdateUser(t *testing.T) {
+	t.Run("given user renames themselves should return 200", func(t *testing.T) {
+		c, rec := newContext(http.MethodPut, `{"id": 2, "name": "Jane Smith"}`, auth.Principal{UserID: 2})
+
+		serve(c, New(StubUser{users: users}).UpdateUserHandler)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given user renames someone else should return 403", func(t *testing.T) {
+		c, rec := newContext(http.MethodPut, `{"id": 1, "name": "Jane Smith"}`, auth.Principal{UserID: 2})
+
+		serve(c, New(StubUser{users: users}).UpdateUserHandler)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given unknown user should return 404", func(t *testing.T) {
+		c, rec := newContext(http.MethodPut, `{"id": 9, "name": "Nobody"}`, admin)
+
+		serve(c, New(StubUser{users: users}).UpdateUserHandler)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given missing id should return 400", func(t *testing.T) {
+		c, rec := newContext(http.MethodPut, `{"name": "Nobody"}`, admin)
+
+		serve(c, New(StubUser{users: users}).UpdateUserHandler)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package wallet
+
+import (
+	"strconv"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/auth"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	errNotOwner      = newError(ErrForbidden, "wallet belongs to another user")
+	errAdminOnly     = newError(ErrForbidden, "admin role required")
+	errInvalidUserID = newError(ErrValidation, "invalid user id")
+)
+
+// principal returns the authenticated caller. Handlers fail closed when
+// no authentication middleware ran.
+func principal(c echo.Context) (auth.Principal, error) {
+	p, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return auth.Principal{}, echo.ErrUnauthorized
+	}
+	return p, nil
+}
+
+// authorize allows the owner of userID's wallets, admins and API keys.
+// API key scopes are checked by auth.RequireScope on the route.
+func authorize(c echo.Context, userID int) error {
+	p, err := principal(c)
+	if err != nil {
+		return err
+	}
+	if p.IsAdmin() || p.IsService() || p.UserID == userID {
+		return nil
+	}
+	return errNotOwner
+}
+
+// authorizeUser is authorize for a user id taken from the path.
+func authorizeUser(c echo.Context, userID string) error {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return errInvalidUserID
+	}
+	return authorize(c, id)
+}
+
+func requireAdmin(c echo.Context) error {
+	p, err := principal(c)
+	if err != nil {
+		return err
+	}
+	if !p.IsAdmin() && !p.IsService() {
+		return errAdminOnly
+	}
+	return nil
+}
+
+// ownedWallet loads a wallet the caller is allowed to act on.
+func (h *Handler) ownedWallet(c echo.Context, id int) (Wallet, error) {
+	wallet, err := h.store.WalletById(c.Request().Context(), id)
+	if err != nil {
+		return Wallet{}, err
+	}
+	if err := authorize(c, wallet.UserID); err != nil {
+		return Wallet{}, err
+	}
+	return wallet, nil
+}
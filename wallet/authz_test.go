@@ -0,0 +1,171 @@
+package wallet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/auth"
+	"github.com/labstack/echo/v4"
+)
+
+func asUser(c echo.Context, userID int) {
+	auth.SetPrincipal(c, auth.Principal{UserID: userID})
+}
+
+func TestOwnership(t *testing.T) {
+	owned := []Wallet{
+		{ID: 1, UserID: 1, WalletName: "savings", WalletType: Savings, Currency: DefaultCurrency},
+		{ID: 2, UserID: 1, WalletName: "card", WalletType: CreditCard, Currency: DefaultCurrency},
+		{ID: 3, UserID: 2, WalletName: "other", WalletType: Savings, Currency: DefaultCurrency},
+	}
+
+	t.Run("given no authenticated caller should return 401", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/wallets", nil), rec)
+
+		if err := New(StubWallet{wallet: owned}).WalletHandler(c); err != nil {
+			ErrorHandler(err, c)
+		}
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given user reads another user's wallet should return 403", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/wallets/3", nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues("3")
+		asUser(c, 1)
+
+		serve(c, New(StubWallet{wallet: owned}).WalletByIdHandler)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given user reads own wallet should return 200", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/wallets/1", nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		asUser(c, 1)
+
+		serve(c, New(StubWallet{wallet: owned}).WalletByIdHandler)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given API key reads any user's wallet should return 200", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/wallets/3", nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues("3")
+		auth.SetPrincipal(c, auth.Principal{KeyID: 1, Scopes: []string{auth.ScopeWalletsRead}})
+
+		serve(c, New(StubWallet{wallet: owned}).WalletByIdHandler)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given user lists wallets should only query own wallets", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/wallets?wallet_type=Savings", nil), rec)
+		asUser(c, 1)
+		var got WalletFilter
+
+		serve(c, New(StubWallet{wallet: owned[:1], gotFilter: &got}).WalletHandler)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d and %s", rec.Code, rec.Body.String())
+		}
+		if got.UserID != 1 || got.WalletType != Savings {
+			t.Errorf("expected own Savings wallets to be queried, got %+v", got)
+		}
+	})
+
+	t.Run("given user lists another user's wallets should return 403", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/wallets?user_id=2", nil), rec)
+		asUser(c, 1)
+
+		serve(c, New(StubWallet{wallet: owned}).WalletHandler)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given user creates wallet for another user should return 403", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets", strings.NewReader(`{"user_id": 2, "user_name": "b", "wallet_name": "w", "wallet_type": "Savings", "balance": 1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		asUser(c, 1)
+
+		serve(c, New(StubWallet{}).CreateWalletHandler)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given user transfers from another user's wallet should return 403", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", strings.NewReader(`{"from_wallet_id": 3, "to_wallet_id": 1, "amount": 10}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		asUser(c, 1)
+
+		serve(c, New(StubWallet{wallet: owned}).TransferHandler)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given user deletes another user's wallets should return 403", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodDelete, "/api/v1/users/2/wallets", nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues("2")
+		asUser(c, 1)
+
+		serve(c, New(StubWallet{}).DeleteWalletByUserIdHandler)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given user reconciles the ledger should return 403", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/ledger/reconciliation", nil), rec)
+		asUser(c, 1)
+
+		serve(c, New(StubWallet{}).ReconcileHandler)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+}
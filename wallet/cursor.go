@@ -0,0 +1,74 @@
+package wallet
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidCursor = NewValidationError("cursor", "is invalid")
+
+// Cursors are opaque to clients so the paging key can change without
+// breaking them.
+func encodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+	id, err := strconv.Atoi(string(raw))
+	if err != nil || id <= 0 {
+		return 0, errInvalidCursor
+	}
+	return id, nil
+}
+
+// Wallet cursors carry the sort and the sort key of the last wallet so a
+// page continues exactly where the previous one ended.
+func encodeWalletCursor(filter WalletFilter, last Wallet) string {
+	var value string
+	switch filter.Sort {
+	case SortBalance:
+		value = last.Balance.String()
+	case SortCreatedAt:
+		value = last.CreatedAt.Format(time.RFC3339Nano)
+	}
+	raw := strings.Join([]string{sortParam(filter), strconv.Itoa(last.ID), value}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeWalletCursor(cursor string, filter WalletFilter) (*WalletCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || parts[0] != sortParam(filter) {
+		return nil, errInvalidCursor
+	}
+	after := &WalletCursor{}
+	if after.ID, err = strconv.Atoi(parts[1]); err != nil || after.ID <= 0 {
+		return nil, errInvalidCursor
+	}
+	switch filter.Sort {
+	case SortBalance:
+		after.Balance, err = ParseMoney(parts[2])
+	case SortCreatedAt:
+		after.CreatedAt, err = time.Parse(time.RFC3339Nano, parts[2])
+	}
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	return after, nil
+}
+
+func sortParam(filter WalletFilter) string {
+	if filter.Desc {
+		return "-" + filter.Sort
+	}
+	return filter.Sort
+}
@@ -0,0 +1,94 @@
+package wallet
+
+import (
+	"errors"
+	"strings"
+)
+
+// Error kinds. Storers and handlers return errors that wrap one of these
+// so ErrorHandler can pick the HTTP status with errors.Is.
+var (
+	ErrNotFound             = errors.New("not found")
+	ErrForbidden            = errors.New("forbidden")
+	ErrConflict             = errors.New("conflict")
+	ErrPreconditionFailed   = errors.New("precondition failed")
+	ErrPreconditionRequired = errors.New("precondition required")
+	ErrValidation           = errors.New("validation failed")
+	ErrUnprocessable        = errors.New("unprocessable")
+)
+
+var (
+	ErrWalletNotFound    = newError(ErrNotFound, "wallet not found")
+	ErrInsufficientFunds = newError(ErrUnprocessable, "insufficient funds")
+	ErrVersionConflict   = newError(ErrConflict, "wallet has been modified by another request")
+	ErrVersionRequired   = newError(ErrPreconditionRequired, "wallet version is required, send If-Match or version")
+	ErrRateUnavailable   = newError(ErrUnprocessable, "exchange rate unavailable")
+	ErrCurrencyChange    = newError(ErrValidation, "wallet currency cannot be changed")
+	ErrBalanceChange     = newError(ErrValidation, "wallet balance cannot be changed, use deposits, withdrawals and transfers")
+	ErrAmountPrecision   = newError(ErrValidation, "amount has more decimal places than the wallet currency allows")
+
+	errInvalidWalletID = newError(ErrValidation, "invalid wallet id")
+)
+
+type kindError struct {
+	kind    error
+	message string
+}
+
+func newError(kind error, message string) error {
+	return &kindError{kind: kind, message: message}
+}
+
+func (e *kindError) Error() string { return e.message }
+
+func (e *kindError) Unwrap() error { return e.kind }
+
+// NotFound returns an error of kind ErrNotFound with the given message.
+func NotFound(message string) error {
+	return newError(ErrNotFound, message)
+}
+
+// Forbidden returns an error of kind ErrForbidden with the given message.
+func Forbidden(message string) error {
+	return newError(ErrForbidden, message)
+}
+
+// Conflict returns an error of kind ErrConflict with the given message.
+func Conflict(message string) error {
+	return newError(ErrConflict, message)
+}
+
+type FieldError struct {
+	Field   string `json:"field,omitempty" example:"wallet_type"`
+	Message string `json:"message" example:"must be one of Savings, Credit Card, Crypto Wallet"`
+}
+
+// ValidationError lists every invalid field of a request. It is of kind
+// ErrValidation.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func NewValidationError(field, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		if f.Field == "" {
+			messages = append(messages, f.Message)
+			continue
+		}
+		messages = append(messages, f.Field+" "+f.Message)
+	}
+	return ErrValidation.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
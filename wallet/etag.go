@@ -0,0 +1,29 @@
+package wallet
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// The ETag of a wallet is its version, so clients can send it back in
+// If-Match to make an update conditional.
+func setETag(c echo.Context, wallet Wallet) {
+	c.Response().Header().Set("ETag", strconv.Quote(strconv.Itoa(wallet.Version)))
+}
+
+// ifMatchVersion returns the version from the If-Match header. A missing
+// header or "*" matches any version.
+func ifMatchVersion(c echo.Context) (int, bool, error) {
+	raw := strings.TrimSpace(c.Request().Header.Get("If-Match"))
+	if raw == "" || raw == "*" {
+		return 0, false, nil
+	}
+	raw = strings.TrimPrefix(raw, "W/")
+	version, err := strconv.Atoi(strings.Trim(raw, `"`))
+	if err != nil || version <= 0 {
+		return 0, false, NewValidationError("If-Match", "must be a wallet ETag")
+	}
+	return version, true, nil
+}
@@ -0,0 +1,29 @@
+package wallet
+
+import (
+	"math/big"
+	"strings"
+)
+
+// rateScale is the number of decimal places kept when a rate is recorded.
+const rateScale = 10
+
+// RateProvider supplies exchange rates for transfers between wallets of
+// different currencies. Rate returns how many units of to one unit of from
+// buys, or ErrRateUnavailable.
+type RateProvider interface {
+	Rate(from, to string) (*big.Rat, error)
+}
+
+// noRates is used when no provider is configured, so only same-currency
+// transfers succeed.
+type noRates struct{}
+
+func (noRates) Rate(from, to string) (*big.Rat, error) {
+	return nil, ErrRateUnavailable
+}
+
+func formatRate(rate *big.Rat) string {
+	s := strings.TrimRight(rate.FloatString(rateScale), "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -1,51 +1,116 @@
 package wallet
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
 
+	"github.com/KKGo-Software-engineering/fun-exercise-api/logging"
 	"github.com/labstack/echo/v4"
 )
 
 type Handler struct {
 	store Storer
+	rates RateProvider
 }
 
 // for implement interface in wallet.go
 type Storer interface {
-	Wallets(walletType string) ([]Wallet, error)
+	Wallets(ctx context.Context, filter WalletFilter) ([]Wallet, error)
 	//CreateWallet(wallet Wallet) error
-	CreateWallet(wallet Wallet) (int, error)
-	UpdateWallet(wallet Wallet) error
-	DeleteWalletByUserId(userId string) error
-	WalletByUserId(userId string) ([]Wallet, error)
+	CreateWallet(ctx context.Context, wallet Wallet) (Wallet, error)
+	UpdateWallet(ctx context.Context, wallet Wallet) (Wallet, error)
+	DeleteWalletByUserId(ctx context.Context, userId string) error
+	WalletByUserId(ctx context.Context, userId string) ([]Wallet, error)
+	Transfer(ctx context.Context, transfer Transfer) (Transfer, error)
+	Reconcile(ctx context.Context) ([]Reconciliation, error)
+	Deposit(ctx context.Context, walletID int, amount Money) (Wallet, error)
+	Withdraw(ctx context.Context, walletID int, amount Money) (Wallet, error)
+	Transactions(ctx context.Context, filter TransactionFilter) ([]Transaction, error)
+	WalletById(ctx context.Context, id int) (Wallet, error)
+	DeleteWallet(ctx context.Context, id int) error
 }
 
-func New(db Storer) *Handler {
-	return &Handler{store: db}
+type Option func(*Handler)
+
+// WithRates sets the exchange rate provider used for cross-currency transfers.
+func WithRates(rates RateProvider) Option {
+	return func(h *Handler) {
+		h.rates = rates
+	}
+}
+
+func New(db Storer, opts ...Option) *Handler {
+	h := &Handler{store: db, rates: noRates{}}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 type Err struct {
-	Message string `json:"message"`
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors,omitempty"`
 }
 
 // WalletHandler
 //
 //	@Summary		Get all wallets
-//	@Description	Get all wallets
+//	@Description	List wallets a page at a time. Callers that are neither admins nor services only see their own wallets.
 //	@Tags			wallet
 //	@Param			wallet_type	query	string	false	"wallet type" Enums(Savings, Credit Card, Crypto Wallet)
+//	@Param			user_id		query	int		false	"owner user id"
+//	@Param			min_balance	query	number	false	"minimum balance"
+//	@Param			max_balance	query	number	false	"maximum balance"
+//	@Param			from		query	string	false	"created at or after (RFC 3339 or YYYY-MM-DD)"
+//	@Param			to			query	string	false	"created before, a date includes the whole day (RFC 3339 or YYYY-MM-DD)"
+//	@Param			sort		query	string	false	"sort order, prefix with - for descending" Enums(id, -id, balance, -balance, created_at, -created_at)
+//	@Param			limit		query	int		false	"page size (default 20, max 100)"
+//	@Param			cursor		query	string	false	"next_cursor from the previous page"
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{object}	Wallet
+//	@Success		200	{object}	WalletPage
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Security		BearerAuth
+//	@Security		ApiKeyAuth
 //	@Router			/api/v1/wallets [get]
+//	@Failure		400	{object}	Err
 //	@Failure		500	{object}	Err
 func (h *Handler) WalletHandler(c echo.Context) error {
-	walletType := c.QueryParam("wallet_type")
-	wallets, err := h.store.Wallets(walletType)
+	filter, err := parseWalletFilter(c)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		return err
+	}
+	p, err := principal(c)
+	if err != nil {
+		return err
+	}
+	if !p.IsAdmin() && !p.IsService() {
+		if filter.UserID != 0 && filter.UserID != p.UserID {
+			return errNotOwner
+		}
+		filter.UserID = p.UserID
 	}
-	return c.JSON(http.StatusOK, wallets)
+
+	// ask for one extra row to find out whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+	wallets, err := h.store.Wallets(c.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	page := WalletPage{Wallets: wallets}
+	if len(wallets) > limit {
+		page.Wallets = wallets[:limit]
+		page.NextCursor = encodeWalletCursor(filter, page.Wallets[limit-1])
+	}
+	if page.Wallets == nil {
+		page.Wallets = []Wallet{}
+	}
+	return c.JSON(http.StatusOK, page)
 }
 
 // CreateWalletHandler
@@ -56,48 +121,184 @@ func (h *Handler) WalletHandler(c echo.Context) error {
 //	@Accept			json
 //	@Produce		json
 //	@Param			wallet	body	Wallet	true	"Wallet object"
+//	@Param			Idempotency-Key	header	string	false	"replays the stored response when retried with the same key"
 //	@Success		201	{object}	Wallet
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Security		BearerAuth
+//	@Security		ApiKeyAuth
 //	@Router			/api/v1/wallets [post]
 //	@Failure		400	{object}	Err
+//	@Failure		409	{object}	Err
 //	@Failure		500	{object}	Err
 func (h *Handler) CreateWalletHandler(c echo.Context) error {
 	var wallet Wallet
 	if err := c.Bind(&wallet); err != nil {
-		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+		return err
 	}
-	id, err := h.store.CreateWallet(wallet)
-
+	if err := c.Validate(&wallet); err != nil {
+		return err
+	}
+	if err := authorize(c, wallet.UserID); err != nil {
+		return err
+	}
+	wallet.Currency = NormalizeCurrency(wallet.Currency)
+	if wallet.Currency == "" {
+		wallet.Currency = DefaultCurrency
+	}
+	if !ValidCurrency(wallet.Currency) {
+		return NewValidationError("currency", "must be an ISO 4217 code")
+	}
+	if !FitsCurrency(wallet.Balance, wallet.Currency) {
+		return ErrAmountPrecision
+	}
+	created, err := h.store.CreateWallet(c.Request().Context(), wallet)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		return err
 	}
-	wallet.ID = id
-	return c.JSON(http.StatusCreated, wallet)
+	logging.With(c, "wallet_id", created.ID)
+	setETag(c, created)
+	return c.JSON(http.StatusCreated, created)
 }
 
 // UpdateWalletHandler
 //
 //	@Summary		Update wallet
-//	@Description	Update wallet
+//	@Description	Update the owner, name and type of a wallet. The current version is required, either in If-Match or in the version field, so concurrent changes are rejected. The currency cannot be changed and a balance, if sent, must match the stored one.
 //	@Tags			wallet
 //	@Accept			json
 //	@Produce		json
-//	@Param			wallet	body	Wallet	true	"Wallet object"
+//	@Param			If-Match	header	string	false	"ETag of the wallet being updated"
+//	@Param			wallet		body	WalletUpdate	true	"Wallet object"
+//	@Param			Idempotency-Key	header	string	false	"replays the stored response when retried with the same key"
 //	@Success		200	{object}	Wallet
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Security		BearerAuth
+//	@Security		ApiKeyAuth
 //	@Router			/api/v1/wallets [put]
 //	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		409	{object}	Err
+//	@Failure		412	{object}	Err
+//	@Failure		428	{object}	Err
 //	@Failure		500	{object}	Err
 func (h *Handler) UpdateWalletHandler(c echo.Context) error {
-	var wallet Wallet
-	if err := c.Bind(&wallet); err != nil {
-		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	var body WalletUpdate
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+	if body.ID <= 0 {
+		return NewValidationError("id", "is required")
+	}
+	logging.With(c, "wallet_id", body.ID)
+	if err := c.Validate(&body); err != nil {
+		return err
+	}
+	// the caller must own the wallet now and after the update
+	current, err := h.ownedWallet(c, body.ID)
+	if err != nil {
+		return err
+	}
+	if err := authorize(c, body.UserID); err != nil {
+		return err
+	}
+	if body.Balance != nil && *body.Balance != current.Balance {
+		return ErrBalanceChange
 	}
 
-	if err := h.store.UpdateWallet(wallet); err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	wallet := Wallet{
+		ID:         body.ID,
+		UserID:     body.UserID,
+		WalletName: body.WalletName,
+		WalletType: body.WalletType,
+		Currency:   NormalizeCurrency(body.Currency),
+		Version:    body.Version,
+	}
+	version, hasIfMatch, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+	if hasIfMatch {
+		wallet.Version = version
 	}
+	if wallet.Version == 0 {
+		return ErrVersionRequired
+	}
+
+	updated, err := h.store.UpdateWallet(c.Request().Context(), wallet)
+	if errors.Is(err, ErrVersionConflict) && hasIfMatch {
+		return newError(ErrPreconditionFailed, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+	setETag(c, updated)
+	return c.JSON(http.StatusOK, updated)
+}
+
+// WalletByIdHandler
+//
+//	@Summary		Get wallet by id
+//	@Description	Get a single wallet. The ETag header carries the wallet version for conditional updates.
+//	@Tags			wallet
+//	@Produce		json
+//	@Param			id	path	int	true	"wallet id"
+//	@Success		200	{object}	Wallet
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Security		BearerAuth
+//	@Security		ApiKeyAuth
+//	@Router			/api/v1/wallets/{id} [get]
+func (h *Handler) WalletByIdHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return errInvalidWalletID
+	}
+	logging.With(c, "wallet_id", id)
+	wallet, err := h.ownedWallet(c, id)
+	if err != nil {
+		return err
+	}
+	setETag(c, wallet)
 	return c.JSON(http.StatusOK, wallet)
 }
 
+// DeleteWalletHandler
+//
+//	@Summary		Delete wallet by id
+//	@Description	Delete a single wallet. Its remaining balance is booked out in the ledger.
+//	@Tags			wallet
+//	@Produce		json
+//	@Param			id	path	int	true	"wallet id"
+//	@Param			Idempotency-Key	header	string	false	"replays the stored response when retried with the same key"
+//	@Success		204
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Security		BearerAuth
+//	@Security		ApiKeyAuth
+//	@Router			/api/v1/wallets/{id} [delete]
+func (h *Handler) DeleteWalletHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return errInvalidWalletID
+	}
+	logging.With(c, "wallet_id", id)
+	if _, err := h.ownedWallet(c, id); err != nil {
+		return err
+	}
+	if err := h.store.DeleteWallet(c.Request().Context(), id); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
 // DeleteWalletByUserIdHandler
 //
 //	@Summary		Delete wallet by user id
@@ -105,13 +306,22 @@ func (h *Handler) UpdateWalletHandler(c echo.Context) error {
 //	@Tags			user
 //	@Produce		json
 //	@Param			id	path	string	true	"user id"
+//	@Param			Idempotency-Key	header	string	false	"replays the stored response when retried with the same key"
 //	@Success		204	{object}	Err
+//	@Failure		404	{object}	Err
 //	@Failure		500	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Security		BearerAuth
+//	@Security		ApiKeyAuth
 //	@Router			/api/v1/users/{id}/wallets [delete]
 func (h *Handler) DeleteWalletByUserIdHandler(c echo.Context) error {
 	id := c.Param("id")
-	if err := h.store.DeleteWalletByUserId(id); err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	if err := authorizeUser(c, id); err != nil {
+		return err
+	}
+	if err := h.store.DeleteWalletByUserId(c.Request().Context(), id); err != nil {
+		return err
 	}
 	return c.NoContent(http.StatusNoContent)
 }
@@ -125,12 +335,251 @@ func (h *Handler) DeleteWalletByUserIdHandler(c echo.Context) error {
 //	@Param			id	path	string	true	"user id"
 //	@Success		200	{object}	Wallet
 //	@Failure		500	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Security		BearerAuth
+//	@Security		ApiKeyAuth
 //	@Router			/api/v1/users/{id}/wallets [get]
 func (h *Handler) WalletByUserIdHandler(c echo.Context) error {
 	id := c.Param("id")
-	wallet, err := h.store.WalletByUserId(id)
+	if err := authorizeUser(c, id); err != nil {
+		return err
+	}
+	wallet, err := h.store.WalletByUserId(c.Request().Context(), id)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		return err
 	}
 	return c.JSON(http.StatusOK, wallet)
 }
+
+// TransferHandler
+//
+//	@Summary		Transfer between wallets
+//	@Description	Move money from one wallet to another atomically. Amount is in the source wallet currency and is converted when the destination uses another currency.
+//	@Tags			transfer
+//	@Accept			json
+//	@Produce		json
+//	@Param			transfer	body	Transfer	true	"Transfer object"
+//	@Param			Idempotency-Key	header	string	false	"replays the stored response when retried with the same key"
+//	@Success		201	{object}	Transfer
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Security		BearerAuth
+//	@Security		ApiKeyAuth
+//	@Router			/api/v1/transfers [post]
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		422	{object}	Err
+//	@Failure		500	{object}	Err
+func (h *Handler) TransferHandler(c echo.Context) error {
+	var transfer Transfer
+	if err := c.Bind(&transfer); err != nil {
+		return err
+	}
+	logging.With(c, "from_wallet_id", transfer.FromWalletID, "to_wallet_id", transfer.ToWalletID)
+	if transfer.Amount <= 0 {
+		return NewValidationError("amount", "must be greater than zero")
+	}
+	if transfer.FromWalletID == transfer.ToWalletID {
+		return NewValidationError("to_wallet_id", "cannot transfer to the same wallet")
+	}
+
+	from, err := h.ownedWallet(c, transfer.FromWalletID)
+	if err != nil {
+		return err
+	}
+	to, err := h.store.WalletById(c.Request().Context(), transfer.ToWalletID)
+	if err != nil {
+		return err
+	}
+	if err := h.quote(&transfer, from.Currency, to.Currency); err != nil {
+		return err
+	}
+
+	result, err := h.store.Transfer(c.Request().Context(), transfer)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, result)
+}
+
+// quote fills in the credited side of a transfer, converting the amount
+// with the current exchange rate when the wallets use different currencies.
+func (h *Handler) quote(transfer *Transfer, from, to string) error {
+	if !FitsCurrency(transfer.Amount, from) {
+		return ErrAmountPrecision
+	}
+	transfer.Currency = from
+	transfer.CreditCurrency = to
+	transfer.CreditAmount = transfer.Amount
+	transfer.Rate = ""
+	if from == to {
+		return nil
+	}
+
+	rate, err := h.rates.Rate(from, to)
+	if err != nil {
+		return err
+	}
+	transfer.CreditAmount, err = transfer.Amount.Convert(rate, CurrencyPrecision(to))
+	if err != nil {
+		return NewValidationError("amount", "is too large to convert to "+to)
+	}
+	if transfer.CreditAmount <= 0 {
+		return ErrAmountPrecision
+	}
+	transfer.Rate = formatRate(rate)
+	return nil
+}
+
+// ReconcileHandler
+//
+//	@Summary		Reconcile wallet balances
+//	@Description	List wallets whose stored balance disagrees with the ledger
+//	@Tags			ledger
+//	@Produce		json
+//	@Success		200	{array}	Reconciliation
+//	@Failure		500	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Security		BearerAuth
+//	@Security		ApiKeyAuth
+//	@Router			/api/v1/ledger/reconciliation [get]
+func (h *Handler) ReconcileHandler(c echo.Context) error {
+	if err := requireAdmin(c); err != nil {
+		return err
+	}
+	reconciliations, err := h.store.Reconcile(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, reconciliations)
+}
+
+// DepositHandler
+//
+//	@Summary		Deposit into wallet
+//	@Description	Add an amount to the wallet balance
+//	@Tags			wallet
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int			true	"wallet id"
+//	@Param			deposit		body	Movement	true	"Deposit amount"
+//	@Param			Idempotency-Key	header	string	false	"replays the stored response when retried with the same key"
+//	@Success		200	{object}	Wallet
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Security		BearerAuth
+//	@Security		ApiKeyAuth
+//	@Router			/api/v1/wallets/{id}/deposits [post]
+func (h *Handler) DepositHandler(c echo.Context) error {
+	return h.move(c, h.store.Deposit)
+}
+
+// WithdrawHandler
+//
+//	@Summary		Withdraw from wallet
+//	@Description	Subtract an amount from the wallet balance, rejecting overdrafts unless the wallet type allows them
+//	@Tags			wallet
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int			true	"wallet id"
+//	@Param			withdrawal	body	Movement	true	"Withdrawal amount"
+//	@Param			Idempotency-Key	header	string	false	"replays the stored response when retried with the same key"
+//	@Success		200	{object}	Wallet
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		422	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Security		BearerAuth
+//	@Security		ApiKeyAuth
+//	@Router			/api/v1/wallets/{id}/withdrawals [post]
+func (h *Handler) WithdrawHandler(c echo.Context) error {
+	return h.move(c, h.store.Withdraw)
+}
+
+func (h *Handler) move(c echo.Context, apply func(ctx context.Context, walletID int, amount Money) (Wallet, error)) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return errInvalidWalletID
+	}
+	logging.With(c, "wallet_id", id)
+	var movement Movement
+	if err := c.Bind(&movement); err != nil {
+		return err
+	}
+	if movement.Amount <= 0 {
+		return NewValidationError("amount", "must be greater than zero")
+	}
+	if _, err := h.ownedWallet(c, id); err != nil {
+		return err
+	}
+
+	wallet, err := apply(c.Request().Context(), id, movement.Amount)
+	if err != nil {
+		return err
+	}
+	setETag(c, wallet)
+	return c.JSON(http.StatusOK, wallet)
+}
+
+// TransactionHandler
+//
+//	@Summary		Get wallet transactions
+//	@Description	List credits and debits applied to a wallet, newest first
+//	@Tags			wallet
+//	@Produce		json
+//	@Param			id			path	int		true	"wallet id"
+//	@Param			limit		query	int		false	"page size (default 20, max 100)"
+//	@Param			cursor		query	string	false	"next_cursor from the previous page"
+//	@Param			from		query	string	false	"created at or after (RFC 3339 or YYYY-MM-DD)"
+//	@Param			to			query	string	false	"created before, a date includes the whole day (RFC 3339 or YYYY-MM-DD)"
+//	@Param			min_amount	query	number	false	"minimum absolute amount"
+//	@Param			max_amount	query	number	false	"maximum absolute amount"
+//	@Success		200	{object}	TransactionPage
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Security		BearerAuth
+//	@Security		ApiKeyAuth
+//	@Router			/api/v1/wallets/{id}/transactions [get]
+func (h *Handler) TransactionHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return errInvalidWalletID
+	}
+	logging.With(c, "wallet_id", id)
+	filter, err := parseTransactionFilter(c)
+	if err != nil {
+		return err
+	}
+	if _, err := h.ownedWallet(c, id); err != nil {
+		return err
+	}
+	filter.WalletID = id
+
+	// ask for one extra row to find out whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+	transactions, err := h.store.Transactions(c.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	page := TransactionPage{Transactions: transactions}
+	if len(transactions) > limit {
+		page.Transactions = transactions[:limit]
+		page.NextCursor = encodeCursor(page.Transactions[limit-1].ID)
+	}
+	if page.Transactions == nil {
+		page.Transactions = []Transaction{}
+	}
+	return c.JSON(http.StatusOK, page)
+}
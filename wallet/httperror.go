@@ -0,0 +1,66 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/logging"
+	"github.com/labstack/echo/v4"
+)
+
+// ErrorHandler is the Echo HTTPErrorHandler for the API. It maps error
+// kinds to status codes and hides the details of unexpected errors from
+// the client; the request log keeps them.
+func ErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	logging.With(c, "error", err.Error())
+	status, body := errorResponse(err)
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = c.JSON(status, body)
+	}
+	if err != nil {
+		logging.FromContext(c.Request().Context()).Error("writing error response", "error", err)
+	}
+}
+
+func errorResponse(err error) (int, Err) {
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		message := fmt.Sprint(he.Message)
+		if m, ok := he.Message.(string); ok {
+			message = m
+		}
+		return he.Code, Err{Message: message}
+	}
+
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return http.StatusBadRequest, Err{Message: ErrValidation.Error(), Errors: ve.Fields}
+	}
+
+	switch {
+	case errors.Is(err, ErrValidation):
+		return http.StatusBadRequest, Err{Message: err.Error()}
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden, Err{Message: err.Error()}
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound, Err{Message: err.Error()}
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict, Err{Message: err.Error()}
+	case errors.Is(err, ErrPreconditionFailed):
+		return http.StatusPreconditionFailed, Err{Message: err.Error()}
+	case errors.Is(err, ErrPreconditionRequired):
+		return http.StatusPreconditionRequired, Err{Message: err.Error()}
+	case errors.Is(err, ErrUnprocessable):
+		return http.StatusUnprocessableEntity, Err{Message: err.Error()}
+	default:
+		return http.StatusInternalServerError, Err{Message: http.StatusText(http.StatusInternalServerError)}
+	}
+}
@@ -0,0 +1,133 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/auth"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/logging"
+	"github.com/labstack/echo/v4"
+)
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		message string
+	}{
+		{"given wallet not found should return 404", ErrWalletNotFound, http.StatusNotFound, "wallet not found"},
+		{"given wrapped not found should return 404", fmt.Errorf("deposit: %w", ErrWalletNotFound), http.StatusNotFound, "deposit: wallet not found"},
+		{"given custom not found should return 404", NotFound("no wallets found for user id 9"), http.StatusNotFound, "no wallets found for user id 9"},
+		{"given version conflict should return 409", ErrVersionConflict, http.StatusConflict, ErrVersionConflict.Error()},
+		{"given conflict should return 409", Conflict("duplicate key"), http.StatusConflict, "duplicate key"},
+		{"given precondition failed should return 412", newError(ErrPreconditionFailed, "stale"), http.StatusPreconditionFailed, "stale"},
+		{"given precondition required should return 428", ErrVersionRequired, http.StatusPreconditionRequired, ErrVersionRequired.Error()},
+		{"given amount precision should return 400", ErrAmountPrecision, http.StatusBadRequest, ErrAmountPrecision.Error()},
+		{"given insufficient funds should return 422", ErrInsufficientFunds, http.StatusUnprocessableEntity, "insufficient funds"},
+		{"given echo http error should keep its status", echo.NewHTTPError(http.StatusUnsupportedMediaType, "unsupported"), http.StatusUnsupportedMediaType, "unsupported"},
+		{"given unknown error should return 500 without details", errors.New("pq: connection refused"), http.StatusInternalServerError, "Internal Server Error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+			ErrorHandler(tt.err, c)
+
+			if rec.Code != tt.status {
+				t.Errorf("expected %d, got %d", tt.status, rec.Code)
+			}
+			var got Err
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("expected error body, got %s", rec.Body.String())
+			}
+			if got.Message != tt.message {
+				t.Errorf("expected message %q, got %q", tt.message, got.Message)
+			}
+		})
+	}
+
+	t.Run("given validation error should return 400 with field errors", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+
+		verr := NewValidationError("amount", "must be greater than zero")
+		verr.Add("currency", "must be an ISO 4217 code")
+		ErrorHandler(verr, c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", rec.Code)
+		}
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("expected error body, got %s", rec.Body.String())
+		}
+		expected := []FieldError{
+			{Field: "amount", Message: "must be greater than zero"},
+			{Field: "currency", Message: "must be an ISO 4217 code"},
+		}
+		if !reflect.DeepEqual(expected, got.Errors) {
+			t.Errorf("expected field errors %v, got %v", expected, got.Errors)
+		}
+	})
+
+	t.Run("given response already committed should not write again", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+		_ = c.NoContent(http.StatusNoContent)
+
+		ErrorHandler(ErrWalletNotFound, c)
+
+		if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+			t.Errorf("expected untouched 204, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestErrorHandlerLog(t *testing.T) {
+	var out bytes.Buffer
+	stored := Wallet{ID: 7, UserID: 2, Version: 3}
+	e := echo.New()
+	e.Validator = NewValidator()
+	e.HTTPErrorHandler = ErrorHandler
+	e.Use(logging.RequestID, logging.AccessLog(logging.New(&out, "info")))
+	e.PUT("/api/v1/wallets", New(StubWallet{wallet: []Wallet{stored}, updateWallet: stored}).UpdateWalletHandler,
+		auth.Middleware(stubAuthenticator{auth.Principal{UserID: 2}}))
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"id": 7, "user_id": 2, "wallet_name": "Travel", "wallet_type": "Savings"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("If-Match", `"2"`)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var access map[string]any
+	json.Unmarshal(out.Bytes(), &access)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412, got %d and %s", rec.Code, rec.Body.String())
+	}
+	want := map[string]any{"request_id": rec.Header().Get(echo.HeaderXRequestID), "user_id": float64(2), "wallet_id": float64(7), "status": float64(412), "error": ErrVersionConflict.Error()}
+	for k, v := range want {
+		if access[k] != v {
+			t.Errorf("expected %s=%v in the request log, got %v", k, v, access)
+		}
+	}
+}
+
+type stubAuthenticator struct {
+	principal auth.Principal
+}
+
+func (s stubAuthenticator) Authenticate(r *http.Request) (auth.Principal, bool, error) {
+	return s.principal, true, nil
+}
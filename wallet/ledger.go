@@ -0,0 +1,43 @@
+package wallet
+
+import "time"
+
+// Reconciliation reports a wallet whose stored balance disagrees with the
+// sum of its ledger entries.
+type Reconciliation struct {
+	WalletID      int   `json:"wallet_id" example:"1"`
+	Balance       Money `json:"balance" example:"100.00" swaggertype:"number"`
+	LedgerBalance Money `json:"ledger_balance" example:"90.00" swaggertype:"number"`
+	Difference    Money `json:"difference" example:"10.00" swaggertype:"number"`
+}
+
+// Transaction is a single ledger entry applied to a wallet. Amount is
+// positive for credits and negative for debits.
+type Transaction struct {
+	ID            int       `json:"id" example:"42"`
+	TransactionID int       `json:"transaction_id" example:"21"`
+	WalletID      int       `json:"wallet_id" example:"1"`
+	Kind          string    `json:"kind" example:"deposit"`
+	ReferenceID   *int      `json:"reference_id,omitempty" example:"7"`
+	Amount        Money     `json:"amount" example:"-100.00" swaggertype:"number"`
+	Currency      string    `json:"currency" example:"THB"`
+	CreatedAt     time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+// TransactionFilter selects a page of a wallet's history, newest first.
+// Zero values leave the corresponding filter unset; amount bounds apply
+// to the absolute amount.
+type TransactionFilter struct {
+	WalletID  int
+	BeforeID  int
+	Limit     int
+	From      time.Time
+	To        time.Time
+	MinAmount *Money
+	MaxAmount *Money
+}
+
+type TransactionPage struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"next_cursor,omitempty" example:"MTI"`
+}
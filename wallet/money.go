@@ -0,0 +1,234 @@
+package wallet
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount counted in hundredths, matching the
+// DECIMAL(..., 2) balance columns. It marshals to a JSON number such as
+// 100.50 and accepts numbers or numeric strings.
+//
+// Parsing never rounds: input with more than two decimal places is
+// rejected. Computed amounts (for example currency conversions) are
+// rounded half to even with Round.
+type Money int64
+
+const (
+	moneyScale = 2
+	moneyUnit  = 100
+	// maxMoneyDigits keeps parsed amounts well inside int64.
+	maxMoneyDigits = 17
+)
+
+var (
+	errMoneyFormat    = errors.New("invalid money amount")
+	errMoneyPrecision = errors.New("money amount has more than 2 decimal places")
+	errMoneyRange     = errors.New("money amount is out of range")
+
+	// maxMoney bounds computed amounts the same way maxMoneyDigits
+	// bounds parsed ones.
+	maxMoney = new(big.Int).Exp(big.NewInt(10), big.NewInt(maxMoneyDigits), nil)
+)
+
+// ParseMoney parses a decimal string such as "-12.5" or "100.00".
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, errMoneyFormat
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, errMoneyFormat
+	}
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > moneyScale {
+		return 0, errMoneyPrecision
+	}
+	whole = strings.TrimLeft(whole, "0")
+	if len(whole)+moneyScale > maxMoneyDigits {
+		return 0, errMoneyRange
+	}
+
+	digits := whole + frac + strings.Repeat("0", moneyScale-len(frac))
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, errMoneyRange
+	}
+	if negative {
+		n = -n
+	}
+	return Money(n), nil
+}
+
+// MustParseMoney is like ParseMoney but panics on invalid input. It is
+// meant for constants and tests.
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(fmt.Sprintf("wallet: MustParseMoney(%q): %v", s, err))
+	}
+	return m
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) String() string {
+	n := int64(m)
+	sign := ""
+	if n < 0 {
+		sign = "-"
+		n = -n
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, n/moneyUnit, n%moneyUnit)
+}
+
+// Float64 returns the amount in major units. It may lose precision and is
+// only meant for reporting, such as metrics.
+func (m Money) Float64() float64 {
+	return float64(m) / moneyUnit
+}
+
+// Round rounds m to the given number of decimal places using round half
+// to even. Places at or above the Money scale leave m unchanged.
+func (m Money) Round(places int) Money {
+	if places >= moneyScale {
+		return m
+	}
+	if places < 0 {
+		places = 0
+	}
+	step := int64(1)
+	for i := places; i < moneyScale; i++ {
+		step *= 10
+	}
+	n := int64(m)
+	q, r := n/step, n%step
+	if r < 0 {
+		r = -r
+	}
+	switch {
+	case 2*r > step, 2*r == step && q%2 != 0:
+		if n < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Money(q * step)
+}
+
+// Convert multiplies m by rate and rounds the product half to even to the
+// given number of decimal places in a single step. It fails when the
+// result has more digits than a parsed amount may have.
+func (m Money) Convert(rate *big.Rat, places int) (Money, error) {
+	if places > moneyScale {
+		places = moneyScale
+	}
+	if places < 0 {
+		places = 0
+	}
+	step := big.NewInt(1)
+	for i := places; i < moneyScale; i++ {
+		step.Mul(step, big.NewInt(10))
+	}
+
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(int64(m)), rate)
+	den := new(big.Int).Mul(product.Denom(), step)
+	q, r := new(big.Int).QuoRem(product.Num(), den, new(big.Int))
+
+	twice := new(big.Int).Abs(r)
+	twice.Lsh(twice, 1)
+	if cmp := twice.Cmp(den); cmp > 0 || cmp == 0 && q.Bit(0) == 1 {
+		if product.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	q.Mul(q, step)
+	if new(big.Int).Abs(q).Cmp(maxMoney) >= 0 {
+		return 0, errMoneyRange
+	}
+	return Money(q.Int64()), nil
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 1 && data[0] == '"' {
+		unquoted, err := strconv.Unquote(string(data))
+		if err != nil {
+			return errMoneyFormat
+		}
+		data = []byte(unquoted)
+	}
+	// exponent notation such as 1e3 is rejected rather than guessed at
+	parsed, err := ParseMoney(string(data))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner for DECIMAL and integer columns.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		*m = Money(v * moneyUnit)
+		return nil
+	case float64:
+		return m.scanString(strconv.FormatFloat(v, 'f', -1, 64))
+	case nil:
+		*m = 0
+		return nil
+	default:
+		return fmt.Errorf("wallet: cannot scan %T into Money", src)
+	}
+}
+
+func (m *Money) scanString(s string) error {
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value implements driver.Valuer, sending the exact decimal text.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
@@ -0,0 +1,175 @@
+package wallet
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+		err  bool
+	}{
+		{in: "100", want: 10000},
+		{in: "100.5", want: 10050},
+		{in: "0.10", want: 10},
+		{in: "-12.34", want: -1234},
+		{in: ".5", want: 50},
+		{in: "1.230", want: 123},
+		{in: "1.234", err: true},
+		{in: "1e3", err: true},
+		{in: "abc", err: true},
+		{in: "", err: true},
+		{in: "123456789012345678", err: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) expected error, got %v", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseMoney(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := map[Money]string{
+		0:      "0.00",
+		5:      "0.05",
+		10050:  "100.50",
+		-1234:  "-12.34",
+		-5:     "-0.05",
+		999999: "9999.99",
+	}
+	for m, want := range tests {
+		if got := m.String(); got != want {
+			t.Errorf("Money(%d).String() = %s, want %s", int64(m), got, want)
+		}
+	}
+}
+
+func TestMoneyFloat64(t *testing.T) {
+	tests := map[Money]float64{
+		0:     0,
+		10050: 100.5,
+		-1234: -12.34,
+	}
+	for m, want := range tests {
+		if got := m.Float64(); got != want {
+			t.Errorf("Money(%d).Float64() = %v, want %v", int64(m), got, want)
+		}
+	}
+}
+
+func TestMoneyRound(t *testing.T) {
+	tests := []struct {
+		in     string
+		places int
+		want   string
+	}{
+		{"2.50", 0, "2.00"},
+		{"3.50", 0, "4.00"},
+		{"2.51", 0, "3.00"},
+		{"-2.50", 0, "-2.00"},
+		{"-3.50", 0, "-4.00"},
+		{"1.25", 1, "1.20"},
+		{"1.35", 1, "1.40"},
+		{"1.23", 2, "1.23"},
+	}
+	for _, tt := range tests {
+		if got := MustParseMoney(tt.in).Round(tt.places).String(); got != tt.want {
+			t.Errorf("%s.Round(%d) = %s, want %s", tt.in, tt.places, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyConvert(t *testing.T) {
+	tests := []struct {
+		in     string
+		rate   *big.Rat
+		places int
+		want   string
+	}{
+		{"10.00", big.NewRat(145, 4), 2, "362.50"},
+		{"0.01", big.NewRat(1, 2), 2, "0.00"},
+		{"0.03", big.NewRat(1, 2), 2, "0.02"},
+		{"-0.03", big.NewRat(1, 2), 2, "-0.02"},
+		{"100.00", big.NewRat(1495, 10), 0, "14950.00"},
+	}
+	for _, tt := range tests {
+		got, err := MustParseMoney(tt.in).Convert(tt.rate, tt.places)
+		if err != nil || got.String() != tt.want {
+			t.Errorf("%s * %s to %d places = %s, %v, want %s", tt.in, tt.rate, tt.places, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"999999999999999.99", "-999999999999999.99"} {
+		if got, err := MustParseMoney(in).Convert(big.NewRat(1495, 10), 0); err == nil {
+			t.Errorf("%s * 149.5 = %s, want out of range error", in, got)
+		}
+	}
+}
+
+func TestCurrency(t *testing.T) {
+	if !ValidCurrency("THB") || !ValidCurrency("USD") || ValidCurrency("XYZ") {
+		t.Errorf("expected THB and USD to be valid and XYZ invalid")
+	}
+	if NormalizeCurrency(" usd ") != "USD" {
+		t.Errorf("expected currency code to be normalized")
+	}
+	if FitsCurrency(MustParseMoney("10.50"), "JPY") || !FitsCurrency(MustParseMoney("10"), "JPY") {
+		t.Errorf("expected JPY amounts to be whole numbers")
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	t.Run("given money should round trip through JSON exactly", func(t *testing.T) {
+		in := Transfer{Amount: MustParseMoney("0.30")}
+		data, err := json.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got Transfer
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Amount != in.Amount {
+			t.Errorf("expected %s, got %s from %s", in.Amount, got.Amount, data)
+		}
+	})
+
+	t.Run("given quoted amount should parse", func(t *testing.T) {
+		var m Money
+		if err := json.Unmarshal([]byte(`"12.30"`), &m); err != nil || m != 1230 {
+			t.Errorf("expected 12.30, got %s and %v", m, err)
+		}
+	})
+
+	t.Run("given more than 2 decimal places should fail", func(t *testing.T) {
+		var m Money
+		if err := json.Unmarshal([]byte(`0.001`), &m); err == nil {
+			t.Errorf("expected error, got %s", m)
+		}
+	})
+}
+
+func TestMoneyScan(t *testing.T) {
+	var m Money
+	if err := m.Scan([]byte("1000.50")); err != nil || m != 100050 {
+		t.Errorf("expected 1000.50, got %s and %v", m, err)
+	}
+	if err := m.Scan(int64(7)); err != nil || m != 700 {
+		t.Errorf("expected 7.00, got %s and %v", m, err)
+	}
+	if v, err := MustParseMoney("0.1").Value(); err != nil || v != "0.10" {
+		t.Errorf("expected 0.10, got %v and %v", v, err)
+	}
+}
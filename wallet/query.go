@@ -0,0 +1,156 @@
+package wallet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+const dateLayout = "2006-01-02"
+
+func parseLimit(c echo.Context) (int, error) {
+	raw := c.QueryParam("limit")
+	if raw == "" {
+		return defaultPageLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 || limit > maxPageLimit {
+		return 0, NewValidationError("limit", fmt.Sprintf("must be between 1 and %d", maxPageLimit))
+	}
+	return limit, nil
+}
+
+// parseTime accepts RFC 3339 timestamps or plain dates. A plain date used
+// as an upper bound covers the whole day. Timestamps are returned in UTC:
+// created_at has no time zone and Postgres drops the offset of a
+// TIMESTAMP parameter rather than converting it.
+func parseTime(c echo.Context, name string, endOfDay bool) (time.Time, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(dateLayout, raw)
+	if err != nil {
+		return time.Time{}, NewValidationError(name, "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func parseAmount(c echo.Context, name string) (*Money, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return nil, nil
+	}
+	amount, err := ParseMoney(raw)
+	if err != nil || amount < 0 {
+		return nil, NewValidationError(name, "must be a non-negative number")
+	}
+	return &amount, nil
+}
+
+// parseBalance is like parseAmount but allows negative balances.
+func parseBalance(c echo.Context, name string) (*Money, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return nil, nil
+	}
+	balance, err := ParseMoney(raw)
+	if err != nil {
+		return nil, NewValidationError(name, "must be a number")
+	}
+	return &balance, nil
+}
+
+func parseSort(c echo.Context) (string, bool, error) {
+	raw := c.QueryParam("sort")
+	if raw == "" {
+		return SortID, false, nil
+	}
+	key, desc := strings.CutPrefix(raw, "-")
+	switch key {
+	case SortID, SortBalance, SortCreatedAt:
+		return key, desc, nil
+	default:
+		return "", false, NewValidationError("sort", "must be one of id, balance, created_at, optionally prefixed with -")
+	}
+}
+
+func parseTransactionFilter(c echo.Context) (TransactionFilter, error) {
+	var filter TransactionFilter
+	var err error
+
+	if filter.Limit, err = parseLimit(c); err != nil {
+		return filter, err
+	}
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		if filter.BeforeID, err = decodeCursor(cursor); err != nil {
+			return filter, err
+		}
+	}
+	if filter.From, err = parseTime(c, "from", false); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseTime(c, "to", true); err != nil {
+		return filter, err
+	}
+	if filter.MinAmount, err = parseAmount(c, "min_amount"); err != nil {
+		return filter, err
+	}
+	if filter.MaxAmount, err = parseAmount(c, "max_amount"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func parseWalletFilter(c echo.Context) (WalletFilter, error) {
+	var filter WalletFilter
+	var err error
+
+	if filter.Limit, err = parseLimit(c); err != nil {
+		return filter, err
+	}
+	if filter.Sort, filter.Desc, err = parseSort(c); err != nil {
+		return filter, err
+	}
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		if filter.After, err = decodeWalletCursor(cursor, filter); err != nil {
+			return filter, err
+		}
+	}
+	filter.WalletType = c.QueryParam("wallet_type")
+	if filter.WalletType != "" && !ValidWalletType(filter.WalletType) {
+		return filter, NewValidationError("wallet_type", "must be one of "+strings.Join(WalletTypes, ", "))
+	}
+	if raw := c.QueryParam("user_id"); raw != "" {
+		if filter.UserID, err = strconv.Atoi(raw); err != nil || filter.UserID <= 0 {
+			return filter, NewValidationError("user_id", "must be a user id")
+		}
+	}
+	if filter.MinBalance, err = parseBalance(c, "min_balance"); err != nil {
+		return filter, err
+	}
+	if filter.MaxBalance, err = parseBalance(c, "max_balance"); err != nil {
+		return filter, err
+	}
+	if filter.From, err = parseTime(c, "from", false); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseTime(c, "to", true); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
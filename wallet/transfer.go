@@ -0,0 +1,16 @@
+package wallet
+
+import "time"
+
+type Transfer struct {
+	ID           int    `json:"id" example:"1"`
+	FromWalletID int    `json:"from_wallet_id" example:"1"`
+	ToWalletID   int    `json:"to_wallet_id" example:"2"`
+	Amount       Money  `json:"amount" example:"100.00" swaggertype:"number"`
+	Currency     string `json:"currency" example:"USD"`
+	// CreditAmount is what the destination receives, in CreditCurrency.
+	CreditAmount   Money     `json:"credit_amount" example:"3550.00" swaggertype:"number"`
+	CreditCurrency string    `json:"credit_currency" example:"THB"`
+	Rate           string    `json:"rate,omitempty" example:"35.5"`
+	CreatedAt      time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
@@ -0,0 +1,70 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Validator implements echo.Validator. Register it with
+//
+//	e.Validator = wallet.NewValidator()
+//
+// so handlers can call c.Validate after c.Bind. Failures are returned as a
+// *ValidationError naming each field by its JSON name.
+type Validator struct {
+	validate *validator.Validate
+}
+
+func NewValidator() *Validator {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	// the tags are fixed so registering them cannot fail
+	_ = v.RegisterValidation("notblank", func(fl validator.FieldLevel) bool {
+		return strings.TrimSpace(fl.Field().String()) != ""
+	})
+	_ = v.RegisterValidation("wallet_type", func(fl validator.FieldLevel) bool {
+		return ValidWalletType(fl.Field().String())
+	})
+	return &Validator{validate: v}
+}
+
+func (v *Validator) Validate(i any) error {
+	err := v.validate.Struct(i)
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err
+	}
+
+	verr := &ValidationError{}
+	for _, fe := range fieldErrors {
+		verr.Add(fe.Field(), fieldMessage(fe))
+	}
+	return verr
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "notblank":
+		return "is required"
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "max":
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	case "wallet_type":
+		return "must be one of " + strings.Join(WalletTypes, ", ")
+	default:
+		return "is invalid"
+	}
+}
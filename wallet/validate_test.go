@@ -0,0 +1,54 @@
+package wallet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	valid := Wallet{
+		UserID:     1,
+		WalletName: "pingkunga_wallet",
+		WalletType: Savings,
+		Balance:    MustParseMoney("100"),
+	}
+
+	t.Run("given valid wallet without user name should return nil", func(t *testing.T) {
+		if err := NewValidator().Validate(&valid); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		modify func(w *Wallet)
+		want   []FieldError
+	}{
+		{"given missing user id should report user_id", func(w *Wallet) { w.UserID = 0 }, []FieldError{{"user_id", "is required"}}},
+		{"given negative user id should report user_id", func(w *Wallet) { w.UserID = -1 }, []FieldError{{"user_id", "must be greater than 0"}}},
+		{"given blank wallet name should report wallet_name", func(w *Wallet) { w.WalletName = "  " }, []FieldError{{"wallet_name", "is required"}}},
+		{"given unknown wallet type should report wallet_type", func(w *Wallet) { w.WalletType = "Piggy Bank" }, []FieldError{{"wallet_type", "must be one of Savings, Credit Card, Crypto Wallet"}}},
+		{"given negative balance should report balance", func(w *Wallet) { w.Balance = MustParseMoney("-0.01") }, []FieldError{{"balance", "must be greater than or equal to 0"}}},
+		{"given several invalid fields should report all of them", func(w *Wallet) { w.UserID = 0; w.WalletName = "" }, []FieldError{{"user_id", "is required"}, {"wallet_name", "is required"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := valid
+			tt.modify(&w)
+
+			err := NewValidator().Validate(&w)
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected *ValidationError, got %v", err)
+			}
+			if !errors.Is(err, ErrValidation) {
+				t.Errorf("expected error of kind ErrValidation")
+			}
+			if !reflect.DeepEqual(tt.want, verr.Fields) {
+				t.Errorf("expected %v, got %v", tt.want, verr.Fields)
+			}
+		})
+	}
+}
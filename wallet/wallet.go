@@ -2,12 +2,103 @@ package wallet
 
 import "time"
 
+const (
+	Savings      = "Savings"
+	CreditCard   = "Credit Card"
+	CryptoWallet = "Crypto Wallet"
+)
+
+// WalletTypes lists the values accepted for Wallet.WalletType.
+var WalletTypes = []string{Savings, CreditCard, CryptoWallet}
+
 type Wallet struct {
-	ID         int       `json:"id" example:"1"`
-	UserID     int       `json:"user_id" example:"1"`
-	UserName   string    `json:"user_name" example:"John Doe"`
-	WalletName string    `json:"wallet_name" example:"John's Wallet"`
-	WalletType string    `json:"wallet_type" example:"Create Card"`
-	Balance    float64   `json:"balance" example:"100.00"`
+	ID     int `json:"id" example:"1"`
+	UserID int `json:"user_id" example:"1" validate:"required,gt=0"`
+	// UserName is read from the user and ignored on input.
+	UserName   string    `json:"user_name" example:"John Doe" readonly:"true"`
+	WalletName string    `json:"wallet_name" example:"John's Wallet" validate:"required,notblank,max=100"`
+	WalletType string    `json:"wallet_type" example:"Credit Card" validate:"required,wallet_type" enums:"Savings,Credit Card,Crypto Wallet"`
+	Balance    Money     `json:"balance" example:"100.00" swaggertype:"number" validate:"gte=0"`
+	Currency   string    `json:"currency" example:"THB"`
 	CreatedAt  time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+	Version    int       `json:"version" example:"1"`
+}
+
+// WalletUpdate is the body of a wallet update. Only the owner, name and
+// type can change; balances move through deposits, withdrawals and
+// transfers, so a balance sent here must match the stored one.
+type WalletUpdate struct {
+	ID         int    `json:"id" example:"1"`
+	UserID     int    `json:"user_id" example:"1" validate:"required,gt=0"`
+	WalletName string `json:"wallet_name" example:"John's Wallet" validate:"required,notblank,max=100"`
+	WalletType string `json:"wallet_type" example:"Credit Card" validate:"required,wallet_type" enums:"Savings,Credit Card,Crypto Wallet"`
+	Balance    *Money `json:"balance,omitempty" example:"100.00" swaggertype:"number"`
+	Currency   string `json:"currency" example:"THB"`
+	Version    int    `json:"version" example:"1"`
+}
+
+// Sort keys for listing wallets. Every order is broken by id so pages
+// are stable.
+const (
+	SortID        = "id"
+	SortBalance   = "balance"
+	SortCreatedAt = "created_at"
+)
+
+// WalletFilter selects a page of wallets ordered by Sort and then id.
+// Zero values leave the corresponding filter unset.
+type WalletFilter struct {
+	WalletType string
+	UserID     int
+	MinBalance *Money
+	MaxBalance *Money
+	From       time.Time
+	To         time.Time
+	Sort       string
+	Desc       bool
+	// After is the last wallet of the previous page.
+	After *WalletCursor
+	Limit int
+}
+
+// WalletCursor is a wallet's position in the sort order.
+type WalletCursor struct {
+	ID        int
+	Balance   Money
+	CreatedAt time.Time
+}
+
+type WalletPage struct {
+	Wallets    []Wallet `json:"wallets"`
+	NextCursor string   `json:"next_cursor,omitempty" example:"YmFsYW5jZXwxMDAuMDB8Nw"`
+}
+
+// WalletTotal sums the wallets of one type and currency.
+type WalletTotal struct {
+	WalletType string
+	Currency   string
+	Wallets    int
+	Balance    Money
+}
+
+// Movement is a relative amount applied to a wallet balance by a deposit
+// or a withdrawal.
+type Movement struct {
+	Amount Money `json:"amount" example:"100.00" swaggertype:"number"`
+}
+
+// ValidWalletType reports whether t is one of WalletTypes.
+func ValidWalletType(t string) bool {
+	for _, walletType := range WalletTypes {
+		if t == walletType {
+			return true
+		}
+	}
+	return false
+}
+
+// CanOverdraft reports whether a wallet of the given type may go below zero.
+// Only credit cards are allowed to carry a negative balance.
+func CanOverdraft(walletType string) bool {
+	return walletType == CreditCard
 }
@@ -8,9 +8,13 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/KKGo-Software-engineering/fun-exercise-api/auth"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,7 +26,7 @@ type Response struct {
 
 func clientRequest(method, url string, body io.Reader) *Response {
 	req, _ := http.NewRequest(method, url, body)
-	//req.Header.Add("Authorization", os.Getenv("AUTH_TOKEN"))
+	req.Header.Add("Authorization", bearer(1, auth.RoleAdmin))
 	req.Header.Add("Content-Type", "application/json")
 	client := http.Client{}
 	res, err := client.Do(req)
@@ -54,12 +58,36 @@ func uri(paths ...string) string {
 	return baseURL + "/" + strings.Join(paths, "/")
 }
 
+// testKeyFile is the HS256 secret the API under test verifies tokens with.
+const testKeyFile = "../testdata/jwt-test.key"
+
+// bearer signs an Authorization header for userID that expires in an hour.
+func bearer(userID int, roles ...string) string {
+	secret, err := os.ReadFile(testKeyFile)
+	if err != nil {
+		panic(err)
+	}
+	now := time.Now()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		Roles: roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	}).SignedString(bytes.TrimSpace(secret))
+	if err != nil {
+		panic(err)
+	}
+	return "Bearer " + token
+}
+
 // ================================================================
 // Test Method Section
 // ================================================================
 func TestITGetWallets(t *testing.T) {
 	//Arrange
-	var result []Wallet
+	var result WalletPage
 
 	//Act
 	res := clientRequest(http.MethodGet, uri("wallets"), nil)
@@ -69,12 +97,12 @@ func TestITGetWallets(t *testing.T) {
 	assert.Nil(t, err)
 	assert.EqualValues(t, http.StatusOK, res.StatusCode)
 	//ดูว่ามีของคืนมาไหม ไม่สนใจว่า Value ถูกไหม
-	assert.Greater(t, len(result), 0)
+	assert.Greater(t, len(result.Wallets), 0)
 }
 
 func TestITGetWalletByWallerType(t *testing.T) {
 	//Arrange
-	var result []Wallet
+	var result WalletPage
 
 	//Act
 	res := clientRequest(http.MethodGet, uri("wallets?wallet_type=Savings"), nil)
@@ -85,23 +113,47 @@ func TestITGetWalletByWallerType(t *testing.T) {
 	assert.EqualValues(t, http.StatusOK, res.StatusCode)
 
 	//Initial Data มี 2 ตัว
-	assert.Equal(t, len(result), 2)
+	assert.Equal(t, len(result.Wallets), 2)
+}
+
+func TestITGetWalletsPaged(t *testing.T) {
+	//Arrange
+	var first, second WalletPage
+
+	//Act
+	res := clientRequest(http.MethodGet, uri("wallets?limit=2&sort=-balance"), nil)
+	err := res.Decode(&first)
+
+	//Assert
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, res.StatusCode)
+	assert.Len(t, first.Wallets, 2)
+	assert.NotEmpty(t, first.NextCursor)
+
+	//Act
+	res = clientRequest(http.MethodGet, uri("wallets?limit=2&sort=-balance&cursor="+first.NextCursor), nil)
+	err = res.Decode(&second)
+
+	//Assert
+	assert.Nil(t, err)
+	assert.NotEmpty(t, second.Wallets)
+	assert.LessOrEqual(t, int64(second.Wallets[0].Balance), int64(first.Wallets[1].Balance))
+	assert.NotEqual(t, first.Wallets[1].ID, second.Wallets[0].ID)
 }
 
 func TestITCreateWallet(t *testing.T) {
 	//Arrange
 	wallet := Wallet{
-		UserID:     100,
+		UserID:     seedUser(t, "PingkungA"),
 		UserName:   "PingkungA",
 		WalletName: "PingkungA Wallet",
 		WalletType: "Savings",
-		Balance:    1000,
+		Balance:    MustParseMoney("1000"),
 	}
 
 	//Act
 	res := clientRequest(http.MethodPost, uri("wallets"), strings.NewReader(`{
-		"user_id": 100,
-		"user_name": "PingkungA",
+		"user_id": `+strconv.Itoa(wallet.UserID)+`,
 		"wallet_name": "PingkungA Wallet",
 		"wallet_type": "Savings",
 		"balance": 1000
@@ -119,18 +171,67 @@ func TestITCreateWallet(t *testing.T) {
 	assert.Equal(t, wallet.Balance, result.Balance)
 }
 
+func TestITCreateWalletInvalid(t *testing.T) {
+	//Act
+	res := clientRequest(http.MethodPost, uri("wallets"), strings.NewReader(`{
+		"user_id": 1,
+		"wallet_name": "PingkungA Wallet",
+		"wallet_type": "Piggy Bank",
+		"balance": -1
+	}`))
+	var result Err
+	err := res.Decode(&result)
+
+	//Assert
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusBadRequest, res.StatusCode)
+	assert.Len(t, result.Errors, 2)
+}
+
+func TestITCreateWalletIdempotent(t *testing.T) {
+	//Arrange
+	key := "it-create-wallet-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	userID := seedUser(t, "PingkungA")
+	create := func() (*Response, Wallet) {
+		req, _ := http.NewRequest(http.MethodPost, uri("wallets"), strings.NewReader(`{
+			"user_id": `+strconv.Itoa(userID)+`,
+			"wallet_name": "PingkungA Retry Wallet",
+			"wallet_type": "Savings",
+			"balance": 10
+		}`))
+		req.Header.Add("Authorization", bearer(1, auth.RoleAdmin))
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add("Idempotency-Key", key)
+		res, err := http.DefaultClient.Do(req)
+		r := &Response{res, err}
+		var result Wallet
+		r.err = r.Decode(&result)
+		return r, result
+	}
+
+	//Act
+	first, created := create()
+	second, replayed := create()
+
+	//Assert
+	assert.Nil(t, first.err)
+	assert.Nil(t, second.err)
+	assert.EqualValues(t, http.StatusCreated, second.StatusCode)
+	assert.Equal(t, "true", second.Header.Get("Idempotent-Replayed"))
+	assert.Equal(t, created.ID, replayed.ID)
+}
+
 func TestITUpdateWallet(t *testing.T) {
 	//Arrange
 	wallet := seedWallet(t)
-	wallet.Balance = 2000
 
 	//Act
 	res := clientRequest(http.MethodPut, uri("wallets"), strings.NewReader(`{
-		"user_id": 190,
-		"user_name": "PingkungB",
+		"id": `+strconv.Itoa(wallet.ID)+`,
+		"version": `+strconv.Itoa(wallet.Version)+`,
+		"user_id": `+strconv.Itoa(wallet.UserID)+`,
 		"wallet_name": "PingkungB Wallet",
-		"wallet_type": "Savings",
-		"balance": 2000
+		"wallet_type": "Savings"
 	}`))
 	var result Wallet
 	err := res.Decode(&result)
@@ -143,35 +244,199 @@ func TestITUpdateWallet(t *testing.T) {
 	assert.Equal(t, wallet.WalletName, result.WalletName)
 	assert.Equal(t, wallet.WalletType, result.WalletType)
 	assert.Equal(t, wallet.Balance, result.Balance)
+	assert.Equal(t, wallet.Version+1, result.Version)
+}
+
+func TestITUpdateWalletBalance(t *testing.T) {
+	//Arrange
+	wallet := seedWallet(t)
+
+	//Act
+	res := clientRequest(http.MethodPut, uri("wallets"), strings.NewReader(`{
+		"id": `+strconv.Itoa(wallet.ID)+`,
+		"version": `+strconv.Itoa(wallet.Version)+`,
+		"user_id": `+strconv.Itoa(wallet.UserID)+`,
+		"wallet_name": "PingkungB Wallet",
+		"wallet_type": "Savings",
+		"balance": `+(wallet.Balance+MustParseMoney("1000")).String()+`
+	}`))
+
+	//Assert
+	assert.Nil(t, res.err)
+	assert.EqualValues(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestITUpdateWalletStaleVersion(t *testing.T) {
+	//Arrange
+	wallet := seedWallet(t)
+
+	//Act
+	res := clientRequest(http.MethodPut, uri("wallets"), strings.NewReader(`{
+		"id": `+strconv.Itoa(wallet.ID)+`,
+		"version": `+strconv.Itoa(wallet.Version+1)+`,
+		"user_id": `+strconv.Itoa(wallet.UserID)+`,
+		"wallet_name": "PingkungB Wallet",
+		"wallet_type": "Savings"
+	}`))
+
+	//Assert
+	assert.Nil(t, res.err)
+	assert.EqualValues(t, http.StatusConflict, res.StatusCode)
 }
 
 func TestITDeleteWalletByUserID(t *testing.T) {
-	//Arrange UserId = 190
-	seedWallet(t)
+	//Arrange
+	wallet := seedWallet(t)
+
+	//Act
+	res := clientRequest(http.MethodDelete, uri("users", strconv.Itoa(wallet.UserID), "wallets"), nil)
+
+	//Assert
+	assert.EqualValues(t, http.StatusNoContent, res.StatusCode)
+}
+
+func TestITGetAndDeleteWalletByID(t *testing.T) {
+	//Arrange
+	wallet := seedWallet(t)
+	path := uri("wallets", strconv.Itoa(wallet.ID))
+
+	//Act
+	var result Wallet
+	res := clientRequest(http.MethodGet, path, nil)
+	err := res.Decode(&result)
+
+	//Assert
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, wallet.ID, result.ID)
 
 	//Act
-	res := clientRequest(http.MethodDelete, uri("users/190/wallets"), nil)
+	res = clientRequest(http.MethodDelete, path, nil)
 
 	//Assert
 	assert.EqualValues(t, http.StatusNoContent, res.StatusCode)
+	assert.EqualValues(t, http.StatusNotFound, clientRequest(http.MethodGet, path, nil).StatusCode)
+}
+
+func TestITReadiness(t *testing.T) {
+	//Act
+	res, err := http.Get(strings.TrimSuffix(uri(), "/api/v1") + "/readyz")
+
+	//Assert
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.EqualValues(t, http.StatusOK, res.StatusCode)
+}
+
+func TestITMetrics(t *testing.T) {
+	//Act
+	res, err := http.Get(strings.TrimSuffix(uri(), "/api/v1") + "/metrics")
+
+	//Assert
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	assert.EqualValues(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, string(body), `wallet_balance{currency="THB",wallet_type="Savings"}`)
+	assert.Contains(t, string(body), `go_sql_open_connections{db_name="wallet"}`)
+}
+
+func TestITAuthentication(t *testing.T) {
+	send := func(token string) int {
+		req, _ := http.NewRequest(http.MethodGet, uri("users/1/wallets"), nil)
+		if token != "" {
+			req.Header.Add("Authorization", token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	assert.EqualValues(t, http.StatusUnauthorized, send(""))
+	assert.EqualValues(t, http.StatusUnauthorized, send("Bearer not-a-token"))
+	assert.EqualValues(t, http.StatusForbidden, send(bearer(2)))
+	assert.EqualValues(t, http.StatusOK, send(bearer(1, auth.RoleAdmin)))
+}
+
+func TestITAPIKey(t *testing.T) {
+	//Arrange
+	var created struct {
+		ID  int    `json:"id"`
+		Key string `json:"key"`
+	}
+	res := clientRequest(http.MethodPost, uri("api-keys"), strings.NewReader(`{"name": "it-batch", "scopes": ["wallets:read"]}`))
+	assert.Nil(t, res.Decode(&created))
+	assert.EqualValues(t, http.StatusCreated, res.StatusCode)
+	send := func(method, path string, body io.Reader) int {
+		req, _ := http.NewRequest(method, uri(path), body)
+		req.Header.Add("X-API-Key", created.Key)
+		req.Header.Add("Content-Type", "application/json")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	//Act & Assert
+	assert.EqualValues(t, http.StatusOK, send(http.MethodGet, "users/1/wallets", nil))
+	assert.EqualValues(t, http.StatusForbidden, send(http.MethodPost, "transfers", strings.NewReader(`{"from_wallet_id": 1, "to_wallet_id": 2, "amount": 1}`)))
+
+	res = clientRequest(http.MethodDelete, uri("api-keys", strconv.Itoa(created.ID)), nil)
+	assert.EqualValues(t, http.StatusNoContent, res.StatusCode)
+	assert.EqualValues(t, http.StatusUnauthorized, send(http.MethodGet, "users/1/wallets", nil))
+}
+
+func seedUser(t *testing.T, name string) int {
+	var user struct {
+		ID int `json:"id"`
+	}
+	body := bytes.NewBufferString(`{"name": "` + name + `"}`)
+	err := clientRequest(http.MethodPost, uri("users"), body).Decode(&user)
+	if err != nil || user.ID == 0 {
+		t.Fatal("can't create user:", err)
+	}
+	return user.ID
 }
 
 func seedWallet(t *testing.T) Wallet {
 	var walletEntry Wallet
 	body := bytes.NewBufferString(`{
-		"user_id": 190,
-		"user_name": "PingkungB",
+		"user_id": ` + strconv.Itoa(seedUser(t, "PingkungB")) + `,
 		"wallet_name": "PingkungB Wallet",
 		"wallet_type": "Savings",
 		"balance": 1000
 	}`)
 	err := clientRequest(http.MethodPost, uri("wallets"), body).Decode(&walletEntry)
 	if err != nil {
-		t.Fatal("can't create uomer:", err)
+		t.Fatal("can't create wallet:", err)
 	}
 	return walletEntry
 }
 
+func TestITRenameUser(t *testing.T) {
+	//Arrange
+	wallet := seedWallet(t)
+
+	//Act
+	res := clientRequest(http.MethodPut, uri("users"), strings.NewReader(`{
+		"id": `+strconv.Itoa(wallet.UserID)+`,
+		"name": "PingkungB Renamed"
+	}`))
+	res.Body.Close()
+	var result Wallet
+	err := clientRequest(http.MethodGet, uri("wallets", strconv.Itoa(wallet.ID)), nil).Decode(&result)
+
+	//Assert
+	assert.EqualValues(t, http.StatusOK, res.StatusCode)
+	assert.Nil(t, err)
+	assert.Equal(t, "PingkungB Renamed", result.UserName)
+}
+
 func TestGetWalletByUserID(t *testing.T) {
 	//Arrange
 	var result []Wallet
@@ -1,15 +1,19 @@
 package wallet
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/KKGo-Software-engineering/fun-exercise-api/auth"
 	"github.com/labstack/echo/v4"
 )
 
@@ -23,13 +27,9 @@ func TestGetWallet(t *testing.T) {
 
 		stubError := StubWallet{err: errors.New("unable to get wallets")}
 		handler := New(stubError)
-		err := handler.WalletHandler(c)
+		serve(c, handler.WalletHandler)
 
-		if err != nil {
-			t.Errorf("got some error %v", err)
-		}
-
-		if (rec.Code != http.StatusInternalServerError) && (rec.Body.String() != `{"message":"unable to get wallets"}`) {
+		if rec.Code != http.StatusInternalServerError || strings.TrimSpace(rec.Body.String()) != `{"message":"Internal Server Error"}` {
 			t.Errorf("expected 500 and error message, got %d and %s", rec.Code, rec.Body.String())
 		}
 
@@ -48,7 +48,7 @@ func TestGetWallet(t *testing.T) {
 				UserName:   "pingkunga",
 				WalletName: "pingkunga_wallet",
 				WalletType: "Savings",
-				Balance:    99999,
+				Balance:    MustParseMoney("99999"),
 			},
 			{
 				ID:         2,
@@ -56,16 +56,12 @@ func TestGetWallet(t *testing.T) {
 				UserName:   "pingkungb",
 				WalletName: "pingkungb_wallet",
 				WalletType: "Savings",
-				Balance:    99999,
+				Balance:    MustParseMoney("99999"),
 			},
 		}
 		stubWallet := StubWallet{wallet: expected}
 		handler := New(stubWallet)
-		err := handler.WalletHandler(c)
-
-		if err != nil {
-			t.Errorf("got some error %v", err)
-		}
+		serve(c, handler.WalletHandler)
 
 		//Check if the response is 200
 		actualBody := rec.Body.String()
@@ -74,39 +70,138 @@ func TestGetWallet(t *testing.T) {
 		}
 
 		//Convert response to struct wallet
-		var got []Wallet
+		var got WalletPage
 		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
 			t.Errorf("expected list of wallets, got %s", actualBody)
 		}
 
 		//Check if the response is the same as the expected
-		if len(got) != len(expected) {
+		if len(got.Wallets) != len(expected) {
 			t.Errorf("expected list of wallets 2, got empty")
 		}
 
-		if !reflect.DeepEqual(expected, got) {
+		if !reflect.DeepEqual(expected, got.Wallets) || got.NextCursor != "" {
 			t.Errorf("expected list of wallets %v, got %v", expected, got)
 		}
 	})
 
 }
 
+func TestGetWalletPage(t *testing.T) {
+	wallets := []Wallet{
+		{ID: 1, UserID: 1, Balance: MustParseMoney("10")},
+		{ID: 2, UserID: 1, Balance: MustParseMoney("20")},
+		{ID: 3, UserID: 2, Balance: MustParseMoney("20")},
+	}
+	list := func(query string, stub StubWallet) (*httptest.ResponseRecorder, WalletPage) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/wallets?"+query, nil), rec)
+		serve(c, New(stub).WalletHandler)
+		var page WalletPage
+		json.Unmarshal(rec.Body.Bytes(), &page)
+		return rec, page
+	}
+
+	t.Run("given filters and sort should pass them to the store", func(t *testing.T) {
+		var got WalletFilter
+		rec, _ := list("user_id=2&wallet_type=Savings&min_balance=-5&max_balance=100&from=2024-01-01&to=2024-01-31&sort=-created_at", StubWallet{gotFilter: &got})
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d and %s", rec.Code, rec.Body.String())
+		}
+		if got.UserID != 2 || got.WalletType != Savings || got.Sort != SortCreatedAt || !got.Desc {
+			t.Errorf("unexpected filter %+v", got)
+		}
+		if *got.MinBalance != MustParseMoney("-5") || *got.MaxBalance != MustParseMoney("100") {
+			t.Errorf("expected balance range -5 to 100, got %v to %v", *got.MinBalance, *got.MaxBalance)
+		}
+		if got.From.Format("2006-01-02") != "2024-01-01" || got.To.Format("2006-01-02") != "2024-02-01" {
+			t.Errorf("expected created_at range January 2024, got %v to %v", got.From, got.To)
+		}
+		if got.Limit != defaultPageLimit+1 {
+			t.Errorf("expected one extra row to be requested, got limit %d", got.Limit)
+		}
+	})
+
+	t.Run("given client gone should pass the cancelled request context to the store", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/wallets", nil).WithContext(ctx), rec)
+		var got context.Context
+
+		serve(c, New(StubWallet{gotCtx: &got}).WalletHandler)
+
+		if got == nil || got.Err() != context.Canceled {
+			t.Errorf("expected cancelled request context, got %v", got)
+		}
+	})
+
+	t.Run("given more wallets than the limit should return next cursor", func(t *testing.T) {
+		rec, page := list("limit=2&sort=balance", StubWallet{wallet: wallets})
+
+		if rec.Code != http.StatusOK || len(page.Wallets) != 2 || page.NextCursor == "" {
+			t.Fatalf("expected 2 wallets and a cursor, got %d and %s", rec.Code, rec.Body.String())
+		}
+
+		var got WalletFilter
+		list("limit=2&sort=balance&cursor="+page.NextCursor, StubWallet{gotFilter: &got})
+		if got.After == nil || got.After.ID != 2 || got.After.Balance != MustParseMoney("20") {
+			t.Errorf("expected to continue after wallet 2 with balance 20, got %+v", got.After)
+		}
+	})
+
+	t.Run("given timestamps with an offset should pass them to the store in UTC", func(t *testing.T) {
+		var got WalletFilter
+		rec, _ := list("from=2024-01-01T00:00:00%2B07:00&to=2024-01-31T23:00:00-02:00", StubWallet{gotFilter: &got})
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d and %s", rec.Code, rec.Body.String())
+		}
+		from := time.Date(2023, 12, 31, 17, 0, 0, 0, time.UTC)
+		to := time.Date(2024, 2, 1, 1, 0, 0, 0, time.UTC)
+		if got.From != from || got.To != to {
+			t.Errorf("expected %v to %v, got %v to %v", from, to, got.From, got.To)
+		}
+	})
+
+	t.Run("given cursor from another sort should return 400", func(t *testing.T) {
+		_, page := list("limit=2&sort=balance", StubWallet{wallet: wallets})
+
+		rec, _ := list("sort=-balance&cursor="+page.NextCursor, StubWallet{wallet: wallets})
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given invalid query should return 400", func(t *testing.T) {
+		for _, query := range []string{"sort=name", "user_id=abc", "min_balance=lots", "wallet_type=Piggy"} {
+			rec, _ := list(query, StubWallet{wallet: wallets})
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected 400, got %d and %s", query, rec.Code, rec.Body.String())
+			}
+		}
+	})
+}
+
 func TestCreateWallet(t *testing.T) {
 	t.Run("given unable to create wallet should return 500 and error message", func(t *testing.T) {
 		e := echo.New()
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets", nil)
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets", strings.NewReader(`{"id": 1, "user_id": 1, "user_name": "pingkunga", "wallet_name": "pingkunga_wallet", "wallet_type": "Savings", "version": 1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
 		stubError := StubWallet{err: errors.New("unable to create wallet")}
 		handler := New(stubError)
-		err := handler.CreateWalletHandler(c)
-
-		if err != nil {
-			t.Errorf("got some error %v", err)
-		}
+		serve(c, handler.CreateWalletHandler)
 
-		if (rec.Code != http.StatusInternalServerError) && (rec.Body.String() != `{"message":"unable to create wallet"}`) {
+		if rec.Code != http.StatusInternalServerError || strings.TrimSpace(rec.Body.String()) != `{"message":"Internal Server Error"}` {
 			t.Errorf("expected 500 and error message, got %d and %s", rec.Code, rec.Body.String())
 		}
 
@@ -114,9 +209,10 @@ func TestCreateWallet(t *testing.T) {
 
 	t.Run("given user able to create wallet should return wallet created", func(t *testing.T) {
 		e := echo.New()
+		e.Validator = NewValidator()
 
 		//https://stackoverflow.com/questions/76197311/unit-test-for-post-request-is-not-working-in-go
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets", io.NopCloser(strings.NewReader(`{"user_id": 1, "user_name": "pingkunga", "wallet_name": "pingkunga_wallet", "wallet_type": "Savings", "Balance": 99999}`)))
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets", io.NopCloser(strings.NewReader(`{"user_id": 1, "user_name": "pingkunga", "wallet_name": "pingkunga_wallet", "wallet_type": "Savings", "version": 1}`)))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		//ResponseWriter interface and records all the responses from the handler:
 		rec := httptest.NewRecorder()
@@ -128,15 +224,11 @@ func TestCreateWallet(t *testing.T) {
 			UserName:   "pingkunga",
 			WalletName: "pingkunga_wallet",
 			WalletType: "Savings",
-			Balance:    99999,
+			Balance:    MustParseMoney("99999"),
 		}
 		stubWallet := StubWallet{createWallet: expected}
 		handler := New(stubWallet)
-		err := handler.CreateWalletHandler(c)
-
-		if err != nil {
-			t.Errorf("got some error %v", err)
-		}
+		serve(c, handler.CreateWalletHandler)
 
 		//Check if the response is 201
 		actualBody := rec.Body.String()
@@ -160,19 +252,17 @@ func TestCreateWallet(t *testing.T) {
 func TestUpdateWallet(t *testing.T) {
 	t.Run("given unable to update wallet should return 500 and error message", func(t *testing.T) {
 		e := echo.New()
-		req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", nil)
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"id": 1, "user_id": 1, "user_name": "pingkunga", "wallet_name": "pingkunga_wallet", "wallet_type": "Savings", "version": 1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
 		stubError := StubWallet{err: errors.New("unable to update wallet")}
 		handler := New(stubError)
-		err := handler.UpdateWalletHandler(c)
+		serve(c, handler.UpdateWalletHandler)
 
-		if err != nil {
-			t.Errorf("got some error %v", err)
-		}
-
-		if (rec.Code != http.StatusInternalServerError) && (rec.Body.String() != `{"message":"unable to update wallet"}`) {
+		if rec.Code != http.StatusInternalServerError || strings.TrimSpace(rec.Body.String()) != `{"message":"Internal Server Error"}` {
 			t.Errorf("expected 500 and error message, got %d and %s", rec.Code, rec.Body.String())
 		}
 
@@ -180,7 +270,8 @@ func TestUpdateWallet(t *testing.T) {
 
 	t.Run("given user able to update wallet should return wallet updated", func(t *testing.T) {
 		e := echo.New()
-		req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", io.NopCloser(strings.NewReader(`{"ID":1, "user_id": 1, "user_name": "pingkunga_updated", "wallet_name": "pingkunga_wallet", "wallet_type": "Savings", "Balance": 99999}`)))
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", io.NopCloser(strings.NewReader(`{"ID":1, "user_id": 1, "user_name": "pingkunga_updated", "wallet_name": "pingkunga_wallet", "wallet_type": "Savings", "version": 1}`)))
 		//req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		req.Header.Add("Content-Type", "application/json")
 
@@ -193,15 +284,11 @@ func TestUpdateWallet(t *testing.T) {
 			UserName:   "pingkunga_updated",
 			WalletName: "pingkunga_wallet",
 			WalletType: "Savings",
-			Balance:    99999,
+			Balance:    MustParseMoney("99999"),
 		}
 		stubWallet := StubWallet{updateWallet: expected}
 		handler := New(stubWallet)
-		err := handler.UpdateWalletHandler(c)
-
-		if err != nil {
-			t.Errorf("got some error %v", err)
-		}
+		serve(c, handler.UpdateWalletHandler)
 
 		//Check if the response is 200
 		actualBody := rec.Body.String()
@@ -222,22 +309,281 @@ func TestUpdateWallet(t *testing.T) {
 	})
 }
 
+func TestUpdateWalletOverdrawn(t *testing.T) {
+	t.Run("given overdrawn credit card should update it", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"id": 1, "user_id": 1, "wallet_name": "renamed", "wallet_type": "Credit Card", "balance": -250.50, "version": 1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		expected := Wallet{ID: 1, UserID: 1, WalletName: "renamed", WalletType: CreditCard, Balance: MustParseMoney("-250.50")}
+		handler := New(StubWallet{wallet: []Wallet{expected}, updateWallet: expected})
+		serve(c, handler.UpdateWalletHandler)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given a different negative balance should return 400", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"id": 1, "user_id": 1, "wallet_name": "renamed", "wallet_type": "Savings", "balance": -1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(StubWallet{err: errors.New("should not be called")})
+		serve(c, handler.UpdateWalletHandler)
+
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "balance") {
+			t.Errorf("expected 400 for balance, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestUpdateWalletBalance(t *testing.T) {
+	stored := Wallet{ID: 1, UserID: 1, WalletName: "pingkunga_wallet", WalletType: Savings, Balance: MustParseMoney("100.00"), Version: 1}
+
+	t.Run("given unchanged balance should return 200", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"id": 1, "user_id": 1, "wallet_name": "renamed", "wallet_type": "Savings", "balance": 100, "version": 1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(StubWallet{wallet: []Wallet{stored}, updateWallet: stored})
+		serve(c, handler.UpdateWalletHandler)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given changed balance should return 400", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"id": 1, "user_id": 1, "wallet_name": "renamed", "wallet_type": "Savings", "balance": 1000000, "version": 1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(StubWallet{wallet: []Wallet{stored}, updateWallet: stored, err: errors.New("should not be called")})
+		serve(c, handler.UpdateWalletHandler)
+
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "balance cannot be changed") {
+			t.Errorf("expected 400 for balance change, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestUpdateWalletVersion(t *testing.T) {
+	stored := Wallet{
+		ID:         1,
+		UserID:     1,
+		UserName:   "pingkunga",
+		WalletName: "pingkunga_wallet",
+		WalletType: "Savings",
+		Balance:    MustParseMoney("99999"),
+		Version:    3,
+	}
+
+	t.Run("given stale version in body should return 409", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"id": 1, "user_id": 1, "user_name": "pingkunga", "wallet_name": "pingkunga_wallet", "wallet_type": "Savings", "version": 2}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(StubWallet{updateWallet: stored})
+		serve(c, handler.UpdateWalletHandler)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("expected 409, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given stale If-Match should return 412", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"id": 1, "user_id": 1, "user_name": "pingkunga", "wallet_name": "pingkunga_wallet", "wallet_type": "Savings"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("If-Match", `"2"`)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(StubWallet{updateWallet: stored})
+		serve(c, handler.UpdateWalletHandler)
+
+		if rec.Code != http.StatusPreconditionFailed {
+			t.Errorf("expected 412, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given matching If-Match should return 200 with ETag", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"id": 1, "user_id": 1, "user_name": "pingkunga", "wallet_name": "pingkunga_wallet", "wallet_type": "Savings"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("If-Match", `"3"`)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(StubWallet{updateWallet: stored})
+		serve(c, handler.UpdateWalletHandler)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d and %s", rec.Code, rec.Body.String())
+		}
+
+		if got := rec.Header().Get("ETag"); got != `"3"` {
+			t.Errorf("expected ETag \"3\", got %s", got)
+		}
+	})
+
+	t.Run("given neither If-Match nor version should return 428", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"id": 1, "user_id": 1, "user_name": "pingkunga", "wallet_name": "pingkunga_wallet", "wallet_type": "Savings"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(StubWallet{updateWallet: stored, err: errors.New("should not be called")})
+		serve(c, handler.UpdateWalletHandler)
+
+		if rec.Code != http.StatusPreconditionRequired {
+			t.Errorf("expected 428, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given malformed If-Match should return 400", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"id": 1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("If-Match", `"abc"`)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(StubWallet{updateWallet: stored})
+		serve(c, handler.UpdateWalletHandler)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestGetWalletById(t *testing.T) {
+	t.Run("given wallet not found should return 404", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/99", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("99")
+
+		handler := New(StubWallet{walletErr: ErrWalletNotFound})
+		serve(c, handler.WalletByIdHandler)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given user able to get wallet should return wallet with ETag", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/1", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		expected := Wallet{
+			ID:         1,
+			UserID:     1,
+			UserName:   "pingkunga",
+			WalletName: "pingkunga_wallet",
+			WalletType: "Savings",
+			Balance:    MustParseMoney("99999"),
+			Currency:   "THB",
+			Version:    2,
+		}
+		handler := New(StubWallet{wallet: []Wallet{expected}})
+		serve(c, handler.WalletByIdHandler)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d and %s", rec.Code, rec.Body.String())
+		}
+
+		var got Wallet
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected wallet, got %s", rec.Body.String())
+		}
+
+		if !reflect.DeepEqual(expected, got) {
+			t.Errorf("expected wallet %v, got %v", expected, got)
+		}
+
+		if rec.Header().Get("ETag") != `"2"` {
+			t.Errorf("expected ETag \"2\", got %s", rec.Header().Get("ETag"))
+		}
+	})
+}
+
+func TestDeleteWallet(t *testing.T) {
+	t.Run("given wallet not found should return 404", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/wallets/99", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("99")
+
+		handler := New(StubWallet{err: ErrWalletNotFound})
+		serve(c, handler.DeleteWalletHandler)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given user able to delete wallet should return 204", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/wallets/1", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		handler := New(StubWallet{})
+		serve(c, handler.DeleteWalletHandler)
+
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("expected 204, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
 func TestDeleteWalletByUserId(t *testing.T) {
 	t.Run("given unable to delete wallet should return 500 and error message", func(t *testing.T) {
 		e := echo.New()
 		req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/99/wallets", nil)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("99")
 
 		stubError := StubWallet{err: errors.New("unable to delete wallet")}
 		handler := New(stubError)
-		err := handler.DeleteWalletByUserIdHandler(c)
-
-		if err != nil {
-			t.Errorf("got some error %v", err)
-		}
+		serve(c, handler.DeleteWalletByUserIdHandler)
 
-		if (rec.Code != http.StatusInternalServerError) && (rec.Body.String() != `{"message":"unable to delete wallet"}`) {
+		if rec.Code != http.StatusInternalServerError || strings.TrimSpace(rec.Body.String()) != `{"message":"Internal Server Error"}` {
 			t.Errorf("expected 500 and error message, got %d and %s", rec.Code, rec.Body.String())
 		}
 
@@ -248,14 +594,12 @@ func TestDeleteWalletByUserId(t *testing.T) {
 		req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/99/wallets", nil)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("99")
 
 		stubWallet := StubWallet{deleteWallet: "wallet deleted"}
 		handler := New(stubWallet)
-		err := handler.DeleteWalletByUserIdHandler(c)
-
-		if err != nil {
-			t.Errorf("got some error %v", err)
-		}
+		serve(c, handler.DeleteWalletByUserIdHandler)
 
 		//Check if the response is 200
 		actualBody := rec.Body.String()
@@ -264,6 +608,21 @@ func TestDeleteWalletByUserId(t *testing.T) {
 		}
 	})
 
+	t.Run("given user has no wallets should return 404", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/99/wallets", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("99")
+
+		handler := New(StubWallet{err: NotFound("no wallets found for user id 99")})
+		serve(c, handler.DeleteWalletByUserIdHandler)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
 }
 
 func TestGetWalletByUserId(t *testing.T) {
@@ -272,16 +631,14 @@ func TestGetWalletByUserId(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/99/wallets", nil)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("99")
 
 		stubError := StubWallet{err: errors.New("unable to get wallet by user id")}
 		handler := New(stubError)
-		err := handler.WalletByUserIdHandler(c)
-
-		if err != nil {
-			t.Errorf("got some error %v", err)
-		}
+		serve(c, handler.WalletByUserIdHandler)
 
-		if (rec.Code != http.StatusInternalServerError) && (rec.Body.String() != `{"message":"unable to get wallet by user id"}`) {
+		if rec.Code != http.StatusInternalServerError || strings.TrimSpace(rec.Body.String()) != `{"message":"Internal Server Error"}` {
 			t.Errorf("expected 500 and error message, got %d and %s", rec.Code, rec.Body.String())
 		}
 
@@ -292,6 +649,8 @@ func TestGetWalletByUserId(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/99/wallets", nil)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("99")
 
 		expected := []Wallet{
 			{
@@ -300,7 +659,7 @@ func TestGetWalletByUserId(t *testing.T) {
 				UserName:   "pingkunga",
 				WalletName: "pingkunga_wallet",
 				WalletType: "Savings",
-				Balance:    99999,
+				Balance:    MustParseMoney("99999"),
 			},
 			{
 				ID:         2,
@@ -308,17 +667,13 @@ func TestGetWalletByUserId(t *testing.T) {
 				UserName:   "pingkungb",
 				WalletName: "pingkungb_wallet",
 				WalletType: "Savings",
-				Balance:    99999,
+				Balance:    MustParseMoney("99999"),
 			},
 		}
 
 		stubWallet := StubWallet{wallet: expected}
 		handler := New(stubWallet)
-		err := handler.WalletByUserIdHandler(c)
-
-		if err != nil {
-			t.Errorf("got some error %v", err)
-		}
+		serve(c, handler.WalletByUserIdHandler)
 
 		//Check if the response is 200
 		actualBody := rec.Body.String()
@@ -343,32 +698,558 @@ func TestGetWalletByUserId(t *testing.T) {
 	})
 }
 
+func TestTransfer(t *testing.T) {
+	t.Run("given wallet has not enough balance should return 422 and error message", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", strings.NewReader(`{"from_wallet_id": 1, "to_wallet_id": 2, "amount": 500}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		stubError := StubWallet{err: ErrInsufficientFunds}
+		handler := New(stubError)
+		serve(c, handler.TransferHandler)
+
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected 422, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given transfer to the same wallet should return 400", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", strings.NewReader(`{"from_wallet_id": 1, "to_wallet_id": 1, "amount": 500}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(StubWallet{})
+		serve(c, handler.TransferHandler)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given user able to transfer should return transfer created", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", strings.NewReader(`{"from_wallet_id": 1, "to_wallet_id": 2, "amount": 500}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		expected := Transfer{
+			ID:           10,
+			FromWalletID: 1,
+			ToWalletID:   2,
+			Amount:       MustParseMoney("500"),
+		}
+		stubWallet := StubWallet{transfer: expected}
+		handler := New(stubWallet)
+		serve(c, handler.TransferHandler)
+
+		actualBody := rec.Body.String()
+		if rec.Code != http.StatusCreated {
+			t.Errorf("expected 201, got %d and %s", rec.Code, actualBody)
+		}
+
+		var got Transfer
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected transfer created, got %s", actualBody)
+		}
+
+		if !reflect.DeepEqual(expected, got) {
+			t.Errorf("expected transfer created %v, got %v", expected, got)
+		}
+	})
+}
+
+func TestTransferCurrency(t *testing.T) {
+	wallets := []Wallet{
+		{ID: 1, Currency: "USD", WalletType: Savings},
+		{ID: 2, Currency: "THB", WalletType: Savings},
+		{ID: 3, Currency: "JPY", WalletType: Savings},
+	}
+
+	t.Run("given wallets in different currencies should convert with the provider rate", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", strings.NewReader(`{"from_wallet_id": 1, "to_wallet_id": 2, "amount": 10.01}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		var got Transfer
+		stubWallet := StubWallet{wallet: wallets, gotTransfer: &got}
+		handler := New(stubWallet, WithRates(StubRates{"USD/THB": big.NewRat(145, 4)}))
+		serve(c, handler.TransferHandler)
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("expected 201, got %d and %s", rec.Code, rec.Body.String())
+		}
+
+		// 10.01 * 36.25 = 362.8625, rounded half to even to 362.86
+		if got.Currency != "USD" || got.CreditCurrency != "THB" || got.CreditAmount != MustParseMoney("362.86") || got.Rate != "36.25" {
+			t.Errorf("expected USD 10.01 quoted as THB 362.86 at 36.25, got %+v", got)
+		}
+	})
+
+	t.Run("given no rate for the pair should return 422", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", strings.NewReader(`{"from_wallet_id": 1, "to_wallet_id": 3, "amount": 10}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(StubWallet{wallet: wallets})
+		serve(c, handler.TransferHandler)
+
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected 422, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given amount too large for the target currency should return 400", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", strings.NewReader(`{"from_wallet_id": 1, "to_wallet_id": 3, "amount": 999999999999999.99}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		var got Transfer
+		handler := New(StubWallet{wallet: wallets, gotTransfer: &got}, WithRates(StubRates{"USD/JPY": big.NewRat(1495, 10)}))
+		serve(c, handler.TransferHandler)
+
+		if rec.Code != http.StatusBadRequest || got.CreditAmount != 0 {
+			t.Errorf("expected 400 without a transfer, got %d, %s and %+v", rec.Code, rec.Body.String(), got)
+		}
+	})
+
+	t.Run("given amount finer than the source currency should return 400", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", strings.NewReader(`{"from_wallet_id": 3, "to_wallet_id": 1, "amount": 10.5}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(StubWallet{wallet: wallets})
+		serve(c, handler.TransferHandler)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given source wallet not found should return 404", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", strings.NewReader(`{"from_wallet_id": 1, "to_wallet_id": 2, "amount": 10}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(StubWallet{walletErr: ErrWalletNotFound})
+		serve(c, handler.TransferHandler)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestCreateWalletCurrency(t *testing.T) {
+	t.Run("given unknown currency should return 400", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets", strings.NewReader(`{"user_id": 1, "user_name": "pingkunga", "wallet_name": "w", "wallet_type": "Savings", "balance": 1, "currency": "ABC"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(StubWallet{})
+		serve(c, handler.CreateWalletHandler)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestCreateWalletValidation(t *testing.T) {
+	t.Run("given invalid wallet should return 400 with field errors", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets", strings.NewReader(`{"user_name": "pingkunga", "wallet_name": "", "wallet_type": "Piggy Bank", "balance": -1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(StubWallet{err: errors.New("should not be called")})
+		serve(c, handler.CreateWalletHandler)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d and %s", rec.Code, rec.Body.String())
+		}
+
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("expected error body, got %s", rec.Body.String())
+		}
+		fields := []string{}
+		for _, fe := range got.Errors {
+			fields = append(fields, fe.Field)
+		}
+		expected := []string{"user_id", "wallet_name", "wallet_type", "balance"}
+		if !reflect.DeepEqual(expected, fields) {
+			t.Errorf("expected errors for %v, got %v", expected, fields)
+		}
+	})
+
+	t.Run("given update without id should return 400", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = NewValidator()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"user_id": 1, "user_name": "pingkunga", "wallet_name": "w", "wallet_type": "Savings", "balance": 1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(StubWallet{})
+		serve(c, handler.UpdateWalletHandler)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestReconcile(t *testing.T) {
+	t.Run("given unable to reconcile should return 500 and error message", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/ledger/reconciliation", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		stubError := StubWallet{err: errors.New("unable to reconcile")}
+		handler := New(stubError)
+		serve(c, handler.ReconcileHandler)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected 500, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given wallets out of balance should return list of discrepancies", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/ledger/reconciliation", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		expected := []Reconciliation{
+			{WalletID: 1, Balance: MustParseMoney("100"), LedgerBalance: MustParseMoney("90"), Difference: MustParseMoney("10")},
+		}
+		stubWallet := StubWallet{reconciliations: expected}
+		handler := New(stubWallet)
+		serve(c, handler.ReconcileHandler)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d and %s", rec.Code, rec.Body.String())
+		}
+
+		var got []Reconciliation
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected list of discrepancies, got %s", rec.Body.String())
+		}
+
+		if !reflect.DeepEqual(expected, got) {
+			t.Errorf("expected discrepancies %v, got %v", expected, got)
+		}
+	})
+}
+
+func TestDeposit(t *testing.T) {
+	t.Run("given invalid wallet id should return 400", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/abc/deposits", strings.NewReader(`{"amount": 100}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("abc")
+
+		handler := New(StubWallet{})
+		serve(c, handler.DepositHandler)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given wallet not found should return 404", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/99/deposits", strings.NewReader(`{"amount": 100}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("99")
+
+		handler := New(StubWallet{err: ErrWalletNotFound})
+		serve(c, handler.DepositHandler)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given user able to deposit should return wallet with new balance", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/1/deposits", strings.NewReader(`{"amount": 100}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		expected := Wallet{
+			ID:         1,
+			UserID:     1,
+			UserName:   "pingkunga",
+			WalletName: "pingkunga_wallet",
+			WalletType: "Savings",
+			Balance:    MustParseMoney("1100"),
+		}
+		handler := New(StubWallet{updateWallet: expected})
+		serve(c, handler.DepositHandler)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d and %s", rec.Code, rec.Body.String())
+		}
+
+		var got Wallet
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected wallet, got %s", rec.Body.String())
+		}
+
+		if !reflect.DeepEqual(expected, got) {
+			t.Errorf("expected wallet %v, got %v", expected, got)
+		}
+	})
+}
+
+func TestWithdraw(t *testing.T) {
+	t.Run("given overdraft should return 422 and error message", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/1/withdrawals", strings.NewReader(`{"amount": 100000}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		handler := New(StubWallet{err: ErrInsufficientFunds})
+		serve(c, handler.WithdrawHandler)
+
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected 422, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given non positive amount should return 400", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/1/withdrawals", strings.NewReader(`{"amount": -5}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		handler := New(StubWallet{})
+		serve(c, handler.WithdrawHandler)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestCanOverdraft(t *testing.T) {
+	if !CanOverdraft(CreditCard) {
+		t.Errorf("expected credit card to allow overdraft")
+	}
+	if CanOverdraft(Savings) || CanOverdraft(CryptoWallet) {
+		t.Errorf("expected savings and crypto wallet to reject overdraft")
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	t.Run("given invalid cursor should return 400", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/1/transactions?cursor=!!", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		handler := New(StubWallet{})
+		serve(c, handler.TransactionHandler)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given wallet not found should return 404", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/99/transactions", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("99")
+
+		handler := New(StubWallet{err: ErrWalletNotFound})
+		serve(c, handler.TransactionHandler)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d and %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("given more rows than limit should return page with next cursor", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/1/transactions?limit=2&from=2024-01-01&min_amount=10", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		stubWallet := StubWallet{transactions: []Transaction{
+			{ID: 9, TransactionID: 5, WalletID: 1, Kind: "deposit", Amount: MustParseMoney("100")},
+			{ID: 7, TransactionID: 4, WalletID: 1, Kind: "withdrawal", Amount: MustParseMoney("-50")},
+			{ID: 3, TransactionID: 1, WalletID: 1, Kind: "opening", Amount: MustParseMoney("1000")},
+		}}
+		handler := New(stubWallet)
+		serve(c, handler.TransactionHandler)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d and %s", rec.Code, rec.Body.String())
+		}
+
+		var got TransactionPage
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected transaction page, got %s", rec.Body.String())
+		}
+
+		if len(got.Transactions) != 2 {
+			t.Errorf("expected 2 transactions, got %d", len(got.Transactions))
+		}
+
+		if id, err := decodeCursor(got.NextCursor); err != nil || id != 7 {
+			t.Errorf("expected next cursor pointing at 7, got %q", got.NextCursor)
+		}
+	})
+}
+
+// serve runs h the way Echo does, passing any returned error to
+// ErrorHandler. Requests run as an admin unless the test set a caller.
+func serve(c echo.Context, h echo.HandlerFunc) {
+	if _, ok := auth.PrincipalFrom(c); !ok {
+		auth.SetPrincipal(c, auth.Principal{UserID: 1, Roles: []string{auth.RoleAdmin}})
+	}
+	if err := h(c); err != nil {
+		ErrorHandler(err, c)
+	}
+}
+
 // Struct from postgres/wallet.go
 type StubWallet struct {
-	wallet       []Wallet
-	createWallet Wallet
-	updateWallet Wallet
-	deleteWallet string
-	err          error
+	wallet          []Wallet
+	createWallet    Wallet
+	updateWallet    Wallet
+	deleteWallet    string
+	transfer        Transfer
+	reconciliations []Reconciliation
+	transactions    []Transaction
+	walletErr       error
+	gotTransfer     *Transfer
+	gotFilter       *WalletFilter
+	gotCtx          *context.Context
+	err             error
 }
 
 // ล้อกับ type Storer interface in handler.go
-func (s StubWallet) Wallets(walletType string) ([]Wallet, error) {
+func (s StubWallet) Wallets(ctx context.Context, filter WalletFilter) ([]Wallet, error) {
+	if s.gotFilter != nil {
+		*s.gotFilter = filter
+	}
+	if s.gotCtx != nil {
+		*s.gotCtx = ctx
+	}
+	if filter.Limit > 0 && len(s.wallet) > filter.Limit {
+		return s.wallet[:filter.Limit], s.err
+	}
 	return s.wallet, s.err
 }
 
-func (s StubWallet) CreateWallet(wallet Wallet) (int, error) {
-	return 1, s.err
+func (s StubWallet) CreateWallet(ctx context.Context, wallet Wallet) (Wallet, error) {
+	return s.createWallet, s.err
 }
 
-func (s StubWallet) UpdateWallet(wallet Wallet) error {
-	return s.err
+func (s StubWallet) UpdateWallet(ctx context.Context, wallet Wallet) (Wallet, error) {
+	if s.updateWallet.Version != 0 && wallet.Version != 0 && wallet.Version != s.updateWallet.Version {
+		return Wallet{}, ErrVersionConflict
+	}
+	return s.updateWallet, s.err
 }
 
-func (s StubWallet) DeleteWalletByUserId(userId string) error {
+func (s StubWallet) DeleteWalletByUserId(ctx context.Context, userId string) error {
 	return s.err
 }
 
-func (s StubWallet) WalletByUserId(userId string) ([]Wallet, error) {
+func (s StubWallet) WalletByUserId(ctx context.Context, userId string) ([]Wallet, error) {
 	return s.wallet, s.err
 }
+
+func (s StubWallet) Transfer(ctx context.Context, transfer Transfer) (Transfer, error) {
+	if s.gotTransfer != nil {
+		*s.gotTransfer = transfer
+	}
+	return s.transfer, s.err
+}
+
+func (s StubWallet) Reconcile(ctx context.Context) ([]Reconciliation, error) {
+	return s.reconciliations, s.err
+}
+
+func (s StubWallet) Deposit(ctx context.Context, walletID int, amount Money) (Wallet, error) {
+	return s.updateWallet, s.err
+}
+
+func (s StubWallet) Withdraw(ctx context.Context, walletID int, amount Money) (Wallet, error) {
+	return s.updateWallet, s.err
+}
+
+func (s StubWallet) Transactions(ctx context.Context, filter TransactionFilter) ([]Transaction, error) {
+	return s.transactions, s.err
+}
+
+func (s StubWallet) WalletById(ctx context.Context, id int) (Wallet, error) {
+	if s.walletErr != nil {
+		return Wallet{}, s.walletErr
+	}
+	for _, w := range s.wallet {
+		if w.ID == id {
+			return w, nil
+		}
+	}
+	return Wallet{ID: id, Currency: DefaultCurrency}, nil
+}
+
+type StubRates map[string]*big.Rat
+
+func (s StubRates) Rate(from, to string) (*big.Rat, error) {
+	if rate, ok := s[from+"/"+to]; ok {
+		return rate, nil
+	}
+	return nil, ErrRateUnavailable
+}
+
+func (s StubWallet) DeleteWallet(ctx context.Context, id int) error {
+	return s.err
+}
@@ -0,0 +1,395 @@
+// Package wallettest is a contract test suite for wallet.Storer
+// implementations, so every backend behaves the same behind the handlers.
+package wallettest
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+// Setup returns an empty store together with two users that exist in it.
+// Run calls it once per subtest.
+type Setup func(t *testing.T) (store wallet.Storer, userIDs [2]int)
+
+// Run checks that the stores built by setup follow the Storer contract.
+func Run(t *testing.T, setup Setup) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s wallet.Storer, users [2]int)
+	}{
+		{"create", testCreate},
+		{"update", testUpdate},
+		{"list", testList},
+		{"page", testPage},
+		{"by user", testByUser},
+		{"delete by user", testDeleteByUser},
+		{"delete", testDelete},
+		{"not found", testNotFound},
+		{"concurrency", testConcurrency},
+		{"range", testRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, users := setup(t)
+			tt.test(t, store, users)
+			reconciled(t, store)
+		})
+	}
+}
+
+var ctx = context.Background()
+
+func money(s string) wallet.Money {
+	return wallet.MustParseMoney(s)
+}
+
+func create(t *testing.T, s wallet.Storer, userID int, name, walletType, balance string) wallet.Wallet {
+	t.Helper()
+	w, err := s.CreateWallet(ctx, wallet.Wallet{
+		UserID:     userID,
+		WalletName: name,
+		WalletType: walletType,
+		Balance:    money(balance),
+		Currency:   wallet.DefaultCurrency,
+	})
+	if err != nil {
+		t.Fatalf("CreateWallet(%s): %v", name, err)
+	}
+	return w
+}
+
+func ids(wallets []wallet.Wallet) []int {
+	ids := make([]int, len(wallets))
+	for i, w := range wallets {
+		ids[i] = w.ID
+	}
+	return ids
+}
+
+func equalIDs(got []wallet.Wallet, want ...int) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i, w := range got {
+		if w.ID != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// reconciled fails the test when a store operation left a balance that
+// disagrees with the ledger.
+func reconciled(t *testing.T, s wallet.Storer) {
+	t.Helper()
+	differences, err := s.Reconcile(ctx)
+	if err != nil || len(differences) != 0 {
+		t.Errorf("expected balances to match the ledger, got %+v, %v", differences, err)
+	}
+}
+
+func testCreate(t *testing.T, s wallet.Storer, users [2]int) {
+	first := create(t, s, users[0], "Travel", wallet.Savings, "100.50")
+	second := create(t, s, users[0], "Card", wallet.CreditCard, "0")
+
+	if first.ID <= 0 || second.ID <= first.ID {
+		t.Errorf("expected increasing positive ids, got %d and %d", first.ID, second.ID)
+	}
+	if first.Version != 1 || first.CreatedAt.IsZero() || first.UserName == "" {
+		t.Errorf("expected version 1, created_at and user_name, got %+v", first)
+	}
+	if first.Balance != money("100.50") || first.Currency != wallet.DefaultCurrency || first.WalletType != wallet.Savings {
+		t.Errorf("expected the wallet as given, got %+v", first)
+	}
+
+	got, err := s.WalletById(ctx, first.ID)
+	if err != nil || got.WalletName != "Travel" || got.Balance != first.Balance || !got.CreatedAt.Equal(first.CreatedAt) || got.UserName != first.UserName {
+		t.Errorf("expected to read back %+v, got %+v, %v", first, got, err)
+	}
+
+	_, err = s.CreateWallet(ctx, wallet.Wallet{UserID: users[1] + 1000, WalletName: "Ghost", WalletType: wallet.Savings, Currency: wallet.DefaultCurrency})
+	if !errors.Is(err, wallet.ErrValidation) {
+		t.Errorf("expected a validation error for an unknown user, got %v", err)
+	}
+}
+
+func testUpdate(t *testing.T, s wallet.Storer, users [2]int) {
+	w := create(t, s, users[0], "Travel", wallet.Savings, "100")
+
+	w.WalletName = "Holiday"
+	w.WalletType = wallet.CryptoWallet
+	w.UserID = users[1]
+	w.Balance = money("150")
+	updated, err := s.UpdateWallet(ctx, w)
+	if err != nil {
+		t.Fatalf("UpdateWallet: %v", err)
+	}
+	if updated.Version != 2 || updated.WalletName != "Holiday" || updated.WalletType != wallet.CryptoWallet || updated.UserID != users[1] {
+		t.Errorf("expected the update at version 2, got %+v", updated)
+	}
+	if updated.Balance != money("100") {
+		t.Errorf("expected the balance to stay 100, got %v", updated.Balance)
+	}
+	if !updated.CreatedAt.Equal(w.CreatedAt) {
+		t.Errorf("expected created_at to stay %v, got %v", w.CreatedAt, updated.CreatedAt)
+	}
+
+	cases := map[string]struct {
+		change func(w *wallet.Wallet)
+		want   error
+	}{
+		"stale version":   {func(w *wallet.Wallet) { w.Version = 1 }, wallet.ErrVersionConflict},
+		"currency change": {func(w *wallet.Wallet) { w.Currency = "USD" }, wallet.ErrCurrencyChange},
+		"unknown user":    {func(w *wallet.Wallet) { w.UserID = users[1] + 1000 }, wallet.ErrValidation},
+		"missing wallet":  {func(w *wallet.Wallet) { w.ID += 1000 }, wallet.ErrWalletNotFound},
+	}
+	for name, tc := range cases {
+		change := updated
+		tc.change(&change)
+		if _, err := s.UpdateWallet(ctx, change); !errors.Is(err, tc.want) {
+			t.Errorf("%s: expected %v, got %v", name, tc.want, err)
+		}
+	}
+
+	unversioned := updated
+	unversioned.Version = 0
+	if _, err := s.UpdateWallet(ctx, unversioned); !errors.Is(err, wallet.ErrVersionRequired) {
+		t.Errorf("expected ErrVersionRequired without a version, got %v", err)
+	}
+	if got, _ := s.WalletById(ctx, w.ID); got.Version != 2 {
+		t.Errorf("expected the rejected updates to keep version 2, got %d", got.Version)
+	}
+}
+
+func testList(t *testing.T, s wallet.Storer, users [2]int) {
+	a := create(t, s, users[0], "A", wallet.Savings, "300")
+	b := create(t, s, users[0], "B", wallet.CreditCard, "100")
+	c := create(t, s, users[1], "C", wallet.Savings, "200")
+	d := create(t, s, users[1], "D", wallet.CryptoWallet, "100")
+	min, max := money("100"), money("200")
+
+	cases := map[string]struct {
+		filter wallet.WalletFilter
+		want   []int
+	}{
+		"all by id":          {wallet.WalletFilter{}, []int{a.ID, b.ID, c.ID, d.ID}},
+		"wallet_type":        {wallet.WalletFilter{WalletType: wallet.Savings}, []int{a.ID, c.ID}},
+		"user_id":            {wallet.WalletFilter{UserID: users[1]}, []int{c.ID, d.ID}},
+		"type and user":      {wallet.WalletFilter{WalletType: wallet.Savings, UserID: users[1]}, []int{c.ID}},
+		"balance range":      {wallet.WalletFilter{MinBalance: &min, MaxBalance: &max}, []int{b.ID, c.ID, d.ID}},
+		"balance ascending":  {wallet.WalletFilter{Sort: wallet.SortBalance}, []int{b.ID, d.ID, c.ID, a.ID}},
+		"balance descending": {wallet.WalletFilter{Sort: wallet.SortBalance, Desc: true}, []int{a.ID, c.ID, d.ID, b.ID}},
+		"id descending":      {wallet.WalletFilter{Desc: true}, []int{d.ID, c.ID, b.ID, a.ID}},
+		"created from":       {wallet.WalletFilter{From: a.CreatedAt}, []int{a.ID, b.ID, c.ID, d.ID}},
+		"created before":     {wallet.WalletFilter{To: a.CreatedAt}, nil},
+		"limit":              {wallet.WalletFilter{Limit: 2}, []int{a.ID, b.ID}},
+	}
+	for name, tc := range cases {
+		got, err := s.Wallets(ctx, tc.filter)
+		if err != nil || !equalIDs(got, tc.want...) {
+			t.Errorf("%s: expected %v, got %v, %v", name, tc.want, ids(got), err)
+		}
+	}
+
+	got, _ := s.Wallets(ctx, wallet.WalletFilter{UserID: users[0]})
+	if len(got) != 2 || got[0].UserName == "" || got[1].UserName == "" {
+		t.Errorf("expected listed wallets to carry user_name, got %+v", got)
+	}
+}
+
+func testPage(t *testing.T, s wallet.Storer, users [2]int) {
+	for i, balance := range []string{"50", "10", "50", "30", "10"} {
+		create(t, s, users[i%2], "W"+strconv.Itoa(i), wallet.Savings, balance)
+	}
+	all, _ := s.Wallets(ctx, wallet.WalletFilter{Sort: wallet.SortBalance, Desc: true})
+
+	var paged []wallet.Wallet
+	filter := wallet.WalletFilter{Sort: wallet.SortBalance, Desc: true, Limit: 2}
+	for page := 0; page < 5; page++ {
+		wallets, err := s.Wallets(ctx, filter)
+		if err != nil {
+			t.Fatalf("Wallets page %d: %v", page, err)
+		}
+		paged = append(paged, wallets...)
+		if len(wallets) < filter.Limit {
+			break
+		}
+		last := wallets[len(wallets)-1]
+		filter.After = &wallet.WalletCursor{ID: last.ID, Balance: last.Balance, CreatedAt: last.CreatedAt}
+	}
+
+	if !equalIDs(paged, ids(all)...) || len(all) != 5 {
+		t.Errorf("expected pages to cover %v once in order, got %v", ids(all), ids(paged))
+	}
+}
+
+func testByUser(t *testing.T, s wallet.Storer, users [2]int) {
+	a := create(t, s, users[0], "A", wallet.Savings, "1")
+	create(t, s, users[1], "B", wallet.Savings, "1")
+	c := create(t, s, users[0], "C", wallet.Savings, "1")
+
+	got, err := s.WalletByUserId(ctx, strconv.Itoa(users[0]))
+	if err != nil || !equalIDs(got, a.ID, c.ID) {
+		t.Errorf("expected wallets %d and %d, got %v, %v", a.ID, c.ID, ids(got), err)
+	}
+	if got, err := s.WalletByUserId(ctx, strconv.Itoa(users[1]+1000)); err != nil || len(got) != 0 {
+		t.Errorf("expected no wallets for an unknown user, got %v, %v", ids(got), err)
+	}
+	if _, err := s.WalletByUserId(ctx, "abc"); !errors.Is(err, wallet.ErrValidation) {
+		t.Errorf("expected a validation error for a non-numeric user id, got %v", err)
+	}
+}
+
+func testDeleteByUser(t *testing.T, s wallet.Storer, users [2]int) {
+	create(t, s, users[0], "A", wallet.Savings, "10")
+	create(t, s, users[0], "B", wallet.CreditCard, "0")
+	kept := create(t, s, users[1], "C", wallet.Savings, "10")
+
+	if err := s.DeleteWalletByUserId(ctx, strconv.Itoa(users[0])); err != nil {
+		t.Fatalf("DeleteWalletByUserId: %v", err)
+	}
+
+	got, _ := s.Wallets(ctx, wallet.WalletFilter{})
+	if !equalIDs(got, kept.ID) {
+		t.Errorf("expected only wallet %d left, got %v", kept.ID, ids(got))
+	}
+	if err := s.DeleteWalletByUserId(ctx, strconv.Itoa(users[0])); !errors.Is(err, wallet.ErrNotFound) {
+		t.Errorf("expected not found deleting again, got %v", err)
+	}
+	if err := s.DeleteWalletByUserId(ctx, "abc"); !errors.Is(err, wallet.ErrValidation) {
+		t.Errorf("expected a validation error for a non-numeric user id, got %v", err)
+	}
+}
+
+func testDelete(t *testing.T, s wallet.Storer, users [2]int) {
+	w := create(t, s, users[0], "A", wallet.Savings, "10")
+	kept := create(t, s, users[0], "B", wallet.Savings, "10")
+
+	if err := s.DeleteWallet(ctx, w.ID); err != nil {
+		t.Fatalf("DeleteWallet: %v", err)
+	}
+
+	if _, err := s.WalletById(ctx, w.ID); !errors.Is(err, wallet.ErrWalletNotFound) {
+		t.Errorf("expected the deleted wallet to be gone, got %v", err)
+	}
+	if _, err := s.WalletById(ctx, kept.ID); err != nil {
+		t.Errorf("expected wallet %d to be kept, got %v", kept.ID, err)
+	}
+	if err := s.DeleteWallet(ctx, w.ID); !errors.Is(err, wallet.ErrWalletNotFound) {
+		t.Errorf("expected ErrWalletNotFound deleting again, got %v", err)
+	}
+}
+
+func testNotFound(t *testing.T, s wallet.Storer, users [2]int) {
+	if _, err := s.WalletById(ctx, 1); !errors.Is(err, wallet.ErrWalletNotFound) {
+		t.Errorf("WalletById: expected ErrWalletNotFound, got %v", err)
+	}
+	if _, err := s.UpdateWallet(ctx, wallet.Wallet{ID: 1, UserID: users[0], WalletName: "A", WalletType: wallet.Savings, Version: 1}); !errors.Is(err, wallet.ErrWalletNotFound) {
+		t.Errorf("UpdateWallet: expected ErrWalletNotFound, got %v", err)
+	}
+	if err := s.DeleteWallet(ctx, 1); !errors.Is(err, wallet.ErrWalletNotFound) {
+		t.Errorf("DeleteWallet: expected ErrWalletNotFound, got %v", err)
+	}
+	if err := s.DeleteWalletByUserId(ctx, strconv.Itoa(users[0])); !errors.Is(err, wallet.ErrNotFound) {
+		t.Errorf("DeleteWalletByUserId: expected ErrNotFound, got %v", err)
+	}
+	if got, err := s.Wallets(ctx, wallet.WalletFilter{}); err != nil || len(got) != 0 {
+		t.Errorf("Wallets: expected none, got %v, %v", ids(got), err)
+	}
+}
+
+func testConcurrency(t *testing.T, s wallet.Storer, users [2]int) {
+	const n = 20
+	var wg sync.WaitGroup
+	created := make([]wallet.Wallet, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			created[i], errs[i] = s.CreateWallet(ctx, wallet.Wallet{UserID: users[i%2], WalletName: "W" + strconv.Itoa(i), WalletType: wallet.Savings, Balance: money("10"), Currency: wallet.DefaultCurrency})
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[int]bool{}
+	for i, w := range created {
+		if errs[i] != nil || seen[w.ID] {
+			t.Fatalf("expected %d wallets with unique ids, got %+v, %v", n, w, errs[i])
+		}
+		seen[w.ID] = true
+	}
+	if all, _ := s.Wallets(ctx, wallet.WalletFilter{}); len(all) != n {
+		t.Errorf("expected %d wallets listed, got %d", n, len(all))
+	}
+
+	target := created[0]
+	updated := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := target
+			w.WalletName = "Renamed " + strconv.Itoa(i)
+			_, updated[i] = s.UpdateWallet(ctx, w)
+		}(i)
+	}
+	wg.Wait()
+
+	wins := 0
+	for _, err := range updated {
+		switch {
+		case err == nil:
+			wins++
+		case !errors.Is(err, wallet.ErrVersionConflict):
+			t.Errorf("expected ErrVersionConflict for a lost update, got %v", err)
+		}
+	}
+	if got, _ := s.WalletById(ctx, target.ID); wins != 1 || got.Version != 2 {
+		t.Errorf("expected exactly one update of version 1 to win, got %d winners and version %d", wins, got.Version)
+	}
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Deposit(ctx, created[1].ID, money("1")); err != nil {
+				t.Errorf("Deposit: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, _ := s.WalletById(ctx, created[1].ID); got.Balance != money("30") || got.Version != n+1 {
+		t.Errorf("expected every deposit applied, got balance %s at version %d", got.Balance, got.Version)
+	}
+}
+
+// testRange checks that balances and amounts are limited to the
+// DECIMAL(10, 2) range of the postgres schema.
+func testRange(t *testing.T, s wallet.Storer, users [2]int) {
+	if _, err := s.CreateWallet(ctx, wallet.Wallet{UserID: users[0], WalletName: "Huge", WalletType: wallet.Savings, Balance: money("100000000"), Currency: wallet.DefaultCurrency}); !errors.Is(err, wallet.ErrValidation) {
+		t.Errorf("CreateWallet: expected ErrValidation, got %v", err)
+	}
+
+	full := create(t, s, users[0], "Full", wallet.Savings, "99999999.99")
+	other := create(t, s, users[1], "Other", wallet.Savings, "1")
+	if _, err := s.Deposit(ctx, full.ID, money("0.01")); !errors.Is(err, wallet.ErrValidation) {
+		t.Errorf("Deposit: expected ErrValidation, got %v", err)
+	}
+	transfer := wallet.Transfer{FromWalletID: other.ID, ToWalletID: full.ID, Amount: money("1"), Currency: wallet.DefaultCurrency, CreditAmount: money("1"), CreditCurrency: wallet.DefaultCurrency}
+	if _, err := s.Transfer(ctx, transfer); !errors.Is(err, wallet.ErrValidation) {
+		t.Errorf("Transfer: expected ErrValidation, got %v", err)
+	}
+
+	for _, w := range []wallet.Wallet{full, other} {
+		if got, _ := s.WalletById(ctx, w.ID); got.Balance != w.Balance || got.Version != 1 {
+			t.Errorf("expected wallet %d to stay at %s, got %s at version %d", w.ID, w.Balance, got.Balance, got.Version)
+		}
+	}
+}